@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// attachment is the coding standard chosen for one detached repository.
+type attachment struct {
+	repo     string
+	standard codacy.CodingStandard
+	reason   string
+}
+
+// runAttachDetached implements the attach-detached command, which brings
+// repositories that follow no coding standard under governance by applying a
+// standard to them instead of patching them one by one.
+func runAttachDetached(args []string) error {
+	fs := flag.NewFlagSet("attach-detached", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		csID    = fs.Int64("coding-standard-id", 0, "Apply this coding standard to every detached repository (0 = match by language)")
+		mapping = fs.String("map", "", "Explicit repository-to-standard mapping, e.g. repo-a=42,repo-b=7; takes precedence over other choices")
+		yes     = fs.Bool("yes", false, "Apply without asking for confirmation")
+		dryRun  = fs.Bool("dry-run", false, "Show the chosen standards without applying them")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler attach-detached [flags]
+
+Applies a coding standard to the repositories that do not follow any. The
+standard is taken from --map, then --coding-standard-id, and otherwise is
+the effective standard covering most of the repository's languages.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	explicit, err := parseStandardMapping(*mapping)
+	if err != nil {
+		return err
+	}
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+
+	client := conn.client(token)
+	all, err := client.ListCodingStandards(provider, orgName)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int64]codacy.CodingStandard, len(all))
+	var effective []codacy.CodingStandard
+	for _, cs := range all {
+		byID[cs.ID] = cs
+		if !cs.IsDraft {
+			effective = append(effective, cs)
+		}
+	}
+	if *csID != 0 {
+		if _, ok := byID[*csID]; !ok {
+			return fmt.Errorf("coding standard %d not found", *csID)
+		}
+	}
+	for repo, id := range explicit {
+		if _, ok := byID[id]; !ok {
+			return fmt.Errorf("coding standard %d mapped to %s not found", id, repo)
+		}
+	}
+
+	repos, err := client.ListRepositoriesWithAnalysis(provider, orgName)
+	if err != nil {
+		return err
+	}
+	detached := detachedRepositories(repos)
+	if len(detached) == 0 {
+		fmt.Println("No detached repositories found.")
+		return nil
+	}
+
+	var plan []attachment
+	var unmatched []string
+	for _, r := range detached {
+		repo := r.Repository
+		switch id, ok := explicit[repo.Name]; {
+		case ok:
+			plan = append(plan, attachment{repo.Name, byID[id], "--map"})
+		case *csID != 0:
+			plan = append(plan, attachment{repo.Name, byID[*csID], "--coding-standard-id"})
+		default:
+			cs, n, ok := matchStandard(repo, effective)
+			if !ok {
+				unmatched = append(unmatched, repo.Name)
+				continue
+			}
+			plan = append(plan, attachment{repo.Name, cs,
+				fmt.Sprintf("covers %d of %d language(s)", n, len(repo.Languages))})
+		}
+	}
+
+	fmt.Printf("Found %d detached repository(ies):\n", len(detached))
+	for _, a := range plan {
+		fmt.Printf("  - %s -> %q (ID %d)  [%s]\n", a.repo, a.standard.Name, a.standard.ID, a.reason)
+	}
+	for _, name := range unmatched {
+		fmt.Printf("  - %s -> no standard matches its languages, skipped\n", name)
+	}
+	fmt.Println()
+
+	if len(plan) == 0 {
+		return nil
+	}
+	if *dryRun {
+		fmt.Printf("[dry-run] would apply coding standards to %d repository(ies)\n", len(plan))
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Apply coding standards to %d repository(ies)?", len(plan))) {
+		fmt.Println("Aborted — no repositories changed.")
+		return nil
+	}
+
+	// One call per standard, with all the repositories it is applied to.
+	groups := make(map[int64][]string)
+	var ids []int64
+	for _, a := range plan {
+		if _, ok := groups[a.standard.ID]; !ok {
+			ids = append(ids, a.standard.ID)
+		}
+		groups[a.standard.ID] = append(groups[a.standard.ID], a.repo)
+	}
+
+	var failed int
+	for _, id := range ids {
+		names := groups[id]
+		result, err := client.AddRepositoriesToCodingStandard(provider, orgName, id, names)
+		if err != nil {
+			logger.Warn("Could not apply coding standard", keyStandardID, id, "repositories", names, "error", err)
+			failed += len(names)
+			continue
+		}
+		if len(result.Successful) > 0 {
+			fmt.Printf("Applied %q (ID %d) to %d repo(s): %s\n", byID[id].Name, id,
+				len(result.Successful), strings.Join(result.Successful, ", "))
+		}
+		if len(result.Failed) > 0 {
+			fmt.Printf("Failed to apply %q (ID %d) to %d repo(s): %s\n", byID[id].Name, id,
+				len(result.Failed), strings.Join(result.Failed, ", "))
+			failed += len(result.Failed)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d repository(ies) could not be attached", failed)
+	}
+	return nil
+}
+
+// parseStandardMapping parses a comma-separated list of repo=standardID pairs.
+func parseStandardMapping(s string) (map[string]int64, error) {
+	m := make(map[string]int64)
+	if s == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		repo, id, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || repo == "" {
+			return nil, fmt.Errorf("invalid --map entry %q — expected repo=standardID", pair)
+		}
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --map entry %q: %w", pair, err)
+		}
+		m[repo] = n
+	}
+	return m, nil
+}
+
+// matchStandard returns the standard in standards covering the most languages
+// of repo, along with how many it covers. Ties go to the default standard and
+// then to the lowest ID. ok is false when no standard covers any language.
+func matchStandard(repo codacy.Repository, standards []codacy.CodingStandard) (best codacy.CodingStandard, covered int, ok bool) {
+	for _, cs := range standards {
+		n := 0
+		for _, lang := range repo.Languages {
+			if containsFold(cs.Languages, lang) {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		better := n > covered ||
+			(n == covered && cs.IsDefault && !best.IsDefault) ||
+			(n == covered && cs.IsDefault == best.IsDefault && cs.ID < best.ID)
+		if !ok || better {
+			best, covered, ok = cs, n, true
+		}
+	}
+	return best, covered, ok
+}
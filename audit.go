@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// auditReport is the outcome of the audit command.
+type auditReport struct {
+	Categories []string      `json:"categories"`
+	Standards  []auditTarget `json:"standards"`
+	Detached   []auditTarget `json:"detached,omitempty"`
+}
+
+// auditTarget is the pattern state of one coding standard or repository.
+type auditTarget struct {
+	ID                 int64       `json:"id,omitempty"`
+	Name               string      `json:"name"`
+	LinkedRepositories int         `json:"linkedRepositories,omitempty"`
+	Tools              []auditTool `json:"tools"`
+	PatternsEnabled    int         `json:"patternsEnabled"`
+	PatternsTotal      int         `json:"patternsTotal"`
+	Error              string      `json:"error,omitempty"`
+}
+
+// auditTool is the pattern state of one tool. Patterns are only counted for
+// enabled tools.
+type auditTool struct {
+	UUID            string `json:"uuid"`
+	Name            string `json:"name,omitempty"`
+	Enabled         bool   `json:"enabled"`
+	PatternsEnabled int    `json:"patternsEnabled"`
+	PatternsTotal   int    `json:"patternsTotal"`
+}
+
+// auditTools counts the enabled and total patterns in categories of every
+// enabled tool in tools, using list to get the patterns of a tool.
+func auditTools(t *auditTarget, tools []auditTool, categories []string, list func(toolUUID string) ([]codacy.ConfiguredPattern, error)) error {
+	for _, tool := range tools {
+		if tool.Enabled {
+			patterns, err := list(tool.UUID)
+			if err != nil {
+				return err
+			}
+			for _, p := range patterns {
+				if !containsFold(categories, p.PatternDefinition.Category) {
+					continue
+				}
+				tool.PatternsTotal++
+				if p.Enabled {
+					tool.PatternsEnabled++
+				}
+			}
+		}
+		t.PatternsEnabled += tool.PatternsEnabled
+		t.PatternsTotal += tool.PatternsTotal
+		t.Tools = append(t.Tools, tool)
+	}
+	return nil
+}
+
+// runAudit implements the audit command, which reports how many patterns of
+// the selected categories are enabled in each coding standard and detached
+// repository without changing anything.
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		csID       = fs.Int64("coding-standard-id", 0, "ID of the coding standard to audit (0 = all effective standards)")
+		categories = fs.String("categories", "Security", "Comma-separated pattern categories to audit")
+		detached   = fs.Bool("detached", true, "Also audit repositories that follow no coding standard")
+		verbose    = fs.Bool("verbose", false, "Show every tool, not only the totals")
+		format     = fs.String("format", "text", "Output format: text or json")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler audit [flags]
+
+Shows how many patterns of the given categories (Security by default) are
+enabled in each effective coding standard and in each repository that
+follows no coding standard. Nothing is changed.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if err := validFormat(*format); err != nil {
+		return err
+	}
+	cats := splitList(*categories)
+	if len(cats) == 0 {
+		return fmt.Errorf("--categories must name at least one category")
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+	client := conn.client(token)
+
+	standards, _, err := resolveStandards(client, provider, orgName, *csID)
+	if err != nil {
+		return err
+	}
+	names := toolNames(client)
+	report := auditReport{Categories: cats}
+
+	for _, cs := range standards {
+		if cs.IsDraft && *csID == 0 {
+			continue
+		}
+		t := auditTarget{ID: cs.ID, Name: cs.Name, LinkedRepositories: cs.Meta.LinkedRepositoriesCount}
+		csTools, err := client.ListCodingStandardTools(provider, orgName, cs.ID)
+		if err == nil {
+			tools := make([]auditTool, 0, len(csTools))
+			for _, ct := range csTools {
+				tools = append(tools, auditTool{UUID: ct.UUID, Name: names[ct.UUID], Enabled: ct.IsEnabled})
+			}
+			err = auditTools(&t, tools, cats, func(uuid string) ([]codacy.ConfiguredPattern, error) {
+				return client.ListCodingStandardPatterns(provider, orgName, cs.ID, uuid)
+			})
+		}
+		if err != nil {
+			t.Error = err.Error()
+		}
+		report.Standards = append(report.Standards, t)
+	}
+
+	if *detached && *csID == 0 {
+		repos, err := client.ListRepositoriesWithAnalysis(provider, orgName)
+		if err != nil {
+			return err
+		}
+		for _, r := range detachedRepositories(repos) {
+			repoName := r.Repository.Name
+			t := auditTarget{Name: repoName}
+			repoTools, err := client.ListRepositoryTools(provider, orgName, repoName)
+			if err == nil {
+				tools := make([]auditTool, 0, len(repoTools))
+				for _, rt := range repoTools {
+					tools = append(tools, auditTool{UUID: rt.UUID, Name: rt.Name, Enabled: rt.Settings.IsEnabled})
+				}
+				err = auditTools(&t, tools, cats, func(uuid string) ([]codacy.ConfiguredPattern, error) {
+					return client.ListRepositoryToolPatterns(provider, orgName, repoName, uuid)
+				})
+			}
+			if err != nil {
+				t.Error = err.Error()
+			}
+			report.Detached = append(report.Detached, t)
+		}
+	}
+
+	if *format == "json" {
+		if report.Standards == nil {
+			report.Standards = []auditTarget{}
+		}
+		return printJSON(report)
+	}
+
+	label := strings.Join(cats, ", ")
+	fmt.Printf("%s patterns enabled per coding standard:\n", label)
+	for _, t := range report.Standards {
+		fmt.Printf("  [%d] %s  (%d linked repositories)\n", t.ID, t.Name, t.LinkedRepositories)
+		printAuditTarget(t, *verbose)
+	}
+	if len(report.Standards) == 0 {
+		fmt.Println("  none")
+	}
+	if *detached && *csID == 0 {
+		fmt.Println()
+		fmt.Printf("%s patterns enabled per detached repository:\n", label)
+		for _, t := range report.Detached {
+			fmt.Printf("  %s\n", t.Name)
+			printAuditTarget(t, *verbose)
+		}
+		if len(report.Detached) == 0 {
+			fmt.Println("  none")
+		}
+	}
+	return nil
+}
+
+// printAuditTarget prints the totals of t and, when verbose, every tool.
+func printAuditTarget(t auditTarget, verbose bool) {
+	if t.Error != "" {
+		fmt.Printf("      error: %s\n", t.Error)
+		return
+	}
+	if verbose {
+		for _, tool := range t.Tools {
+			name := tool.Name
+			if name == "" {
+				name = tool.UUID
+			}
+			if !tool.Enabled {
+				fmt.Printf("      %-30s disabled\n", name)
+				continue
+			}
+			fmt.Printf("      %-30s %d/%d\n", name, tool.PatternsEnabled, tool.PatternsTotal)
+		}
+	}
+	fmt.Printf("      total: %d/%d pattern(s) enabled\n", t.PatternsEnabled, t.PatternsTotal)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// auditEntry is one line of the audit log of --audit-log.
+type auditEntry struct {
+	Timestamp          time.Time             `json:"timestamp"`
+	Operator           auditOperator         `json:"operator"`
+	Provider           string                `json:"provider"`
+	Organization       string                `json:"organization"`
+	Action             string                `json:"action"`
+	Target             codacy.MutationTarget `json:"target"`
+	Categories         []string              `json:"categories,omitempty"`
+	Enabled            *bool                 `json:"enabled,omitempty"`
+	Result             string                `json:"result"` // success or failure
+	Error              string                `json:"error,omitempty"`
+	FailedRepositories []string              `json:"failedRepositories,omitempty"`
+}
+
+// auditOperator is the Codacy user whose API token made a change.
+type auditOperator struct {
+	ID    int64  `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	// Error says why the user could not be looked up.
+	Error string `json:"error,omitempty"`
+}
+
+// auditLog appends an entry to a JSON-lines file for every change made
+// through the API. It is only ever appended to.
+type auditLog struct {
+	mu       sync.Mutex
+	f        *os.File
+	writeErr error // first failed write, reported by close
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &auditLog{f: f}, nil
+}
+
+// record appends the entry for mutation m made by operator.
+func (a *auditLog) record(operator auditOperator, m codacy.Mutation) {
+	e := auditEntry{
+		Timestamp:          time.Now().UTC(),
+		Operator:           operator,
+		Provider:           m.Provider,
+		Organization:       m.Organization,
+		Action:             m.Action,
+		Target:             m.Target,
+		Categories:         m.Categories,
+		Enabled:            m.Enabled,
+		Result:             "success",
+		FailedRepositories: m.Failed,
+	}
+	if m.Err != nil {
+		e.Result, e.Error = "failure", m.Err.Error()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		panic(err) // auditEntry always encodes
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.f.Write(append(line, '\n')); err != nil && a.writeErr == nil {
+		a.writeErr = fmt.Errorf("writing audit log: %w", err)
+	}
+}
+
+// close closes the log and returns the first error writing to it, since a
+// change missing from the log must not go unnoticed.
+func (a *auditLog) close() error {
+	err := a.f.Close()
+	if a.writeErr != nil {
+		return a.writeErr
+	}
+	if err != nil {
+		return fmt.Errorf("closing audit log: %w", err)
+	}
+	return nil
+}
+
+// operatorOf looks up the user the API token of client belongs to.
+func operatorOf(client *codacy.Client) auditOperator {
+	u, err := client.GetUser()
+	if err != nil {
+		return auditOperator{Error: err.Error()}
+	}
+	return auditOperator{ID: u.ID, Name: u.Name, Email: u.MainEmail}
+}
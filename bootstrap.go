@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// recommendedSecurityTools are the names of the tools enabled by
+// bootstrap-standard when --tools is not given. Only those supporting one of
+// the standard's languages, or not tied to any language, are used.
+var recommendedSecurityTools = []string{
+	"Semgrep",
+	"Trivy",
+	"Checkov",
+	"Bandit",
+	"Gosec",
+	"Brakeman",
+	"SpotBugs",
+}
+
+// runBootstrapStandard implements the bootstrap-standard command, which
+// creates a security-focused coding standard for organisations that have none.
+func runBootstrapStandard(args []string) error {
+	fs := flag.NewFlagSet("bootstrap-standard", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		name      = fs.String("name", "Security", "Name of the new coding standard")
+		languages = fs.String("languages", "", "Comma-separated languages of the new coding standard (required)")
+		toolList  = fs.String("tools", "", "Comma-separated tool names or UUIDs to enable (default: recommended security tools for the languages)")
+		makeDef   = fs.Bool("default", false, "Make the new standard the default for new repositories")
+		repoList  = fs.String("repositories", "", "Comma-separated repositories to link to the new standard")
+		promote   = fs.Bool("promote", true, "Promote the new draft to an effective coding standard")
+		dryRun    = fs.Bool("dry-run", false, "Show what would be created without making any changes")
+		yes       = fs.Bool("yes", false, "Create without asking for confirmation")
+		verbose   = fs.Bool("verbose", false, "Log every tool update (same as --log-level=debug)")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler bootstrap-standard [flags]
+
+Creates a new coding standard for the given languages, enables a set of
+security tools with all of their Security patterns, promotes it and
+optionally makes it the default and links repositories to it.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *verbose {
+		*conn.logLevel = "debug"
+	}
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+	langs := splitList(*languages)
+	if len(langs) == 0 {
+		fs.Usage()
+		return fmt.Errorf("--languages is required")
+	}
+	repos := splitList(*repoList)
+	if !*promote && (*makeDef || len(repos) > 0) {
+		return fmt.Errorf("--default and --repositories need --promote")
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+
+	client := conn.client(token)
+	catalogue, err := client.ListTools()
+	if err != nil {
+		return err
+	}
+	tools, err := selectBootstrapTools(catalogue, splitList(*toolList), langs)
+	if err != nil {
+		return err
+	}
+	if len(tools) == 0 {
+		return fmt.Errorf("none of the recommended security tools supports %s — use --tools", strings.Join(langs, ", "))
+	}
+
+	fmt.Printf("Coding standard %q for %s\n", *name, strings.Join(langs, ", "))
+	fmt.Printf("  Tools to enable (%d):\n", len(tools))
+	for _, t := range tools {
+		fmt.Printf("    - %s (%s)\n", t.Name, t.UUID)
+	}
+	if len(repos) > 0 {
+		fmt.Printf("  Repositories to link: %s\n", strings.Join(repos, ", "))
+	}
+	fmt.Printf("  Default: %v   Promote: %v\n", *makeDef, *promote)
+	fmt.Println()
+	if *dryRun {
+		fmt.Println("[dry-run] no changes made")
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Create coding standard %q?", *name)) {
+		fmt.Println("Aborted — nothing created.")
+		return nil
+	}
+
+	cs, err := client.CreateCodingStandard(provider, orgName, *name, langs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Draft created: %q (ID %d)\n", cs.Name, cs.ID)
+	logger = logger.With(keyDraftID, cs.ID)
+
+	var failedTools []string
+	for _, t := range tools {
+		toolLogger := logger.With(keyToolUUID, t.UUID, "tool", t.Name)
+		toolLogger.Debug("Enabling tool and its security patterns")
+		if err := client.UpdateCodingStandardTool(provider, orgName, cs.ID, t.UUID, true, nil); err != nil {
+			toolLogger.Warn("Could not enable tool", "error", err)
+			failedTools = append(failedTools, t.Name)
+			continue
+		}
+		if err := client.UpdateSecurityPatterns(provider, orgName, cs.ID, t.UUID, true); err != nil {
+			toolLogger.Warn("Could not enable security patterns", "error", err)
+			failedTools = append(failedTools, t.Name)
+		}
+	}
+	fmt.Printf("Enabled %d/%d tool(s) with their security patterns\n", len(tools)-len(failedTools), len(tools))
+
+	if len(failedTools) > 0 {
+		return fmt.Errorf("draft %d left unpromoted — could not configure %s", cs.ID, strings.Join(failedTools, ", "))
+	}
+	if !*promote {
+		fmt.Printf("Draft %d left unpromoted (--promote=false)\n", cs.ID)
+		return nil
+	}
+	if _, err := client.PromoteDraftCodingStandard(provider, orgName, cs.ID); err != nil {
+		return err
+	}
+	fmt.Println("Promoted successfully!")
+
+	if *makeDef {
+		if err := client.SetDefaultCodingStandard(provider, orgName, cs.ID); err != nil {
+			return err
+		}
+		fmt.Println("Set as the default coding standard")
+	}
+	if len(repos) > 0 {
+		result, err := client.AddRepositoriesToCodingStandard(provider, orgName, cs.ID, repos)
+		if err != nil {
+			return err
+		}
+		if len(result.Successful) > 0 {
+			fmt.Printf("Linked %d repo(s): %s\n", len(result.Successful), strings.Join(result.Successful, ", "))
+		}
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("could not link %d repo(s): %s", len(result.Failed), strings.Join(result.Failed, ", "))
+		}
+	}
+	return nil
+}
+
+// selectBootstrapTools resolves the tools to enable. Explicit names or UUIDs
+// must all exist in the catalogue; without them the recommended tools that
+// support one of languages, or no language in particular, are used.
+func selectBootstrapTools(catalogue []codacy.Tool, wanted, languages []string) ([]codacy.Tool, error) {
+	matches := func(t codacy.Tool, s string) bool {
+		return t.UUID == s || strings.EqualFold(t.Name, s) || strings.EqualFold(t.ShortName, s)
+	}
+
+	var tools []codacy.Tool
+	if len(wanted) > 0 {
+		for _, w := range wanted {
+			i := slices.IndexFunc(catalogue, func(t codacy.Tool) bool { return matches(t, w) })
+			if i < 0 {
+				return nil, fmt.Errorf("tool %q not found", w)
+			}
+			tools = append(tools, catalogue[i])
+		}
+		return tools, nil
+	}
+
+	for _, t := range catalogue {
+		if !slices.ContainsFunc(recommendedSecurityTools, func(name string) bool { return matches(t, name) }) {
+			continue
+		}
+		if len(t.Languages) == 0 || sharesLanguage(t.Languages, languages) {
+			tools = append(tools, t)
+		}
+	}
+	return tools, nil
+}
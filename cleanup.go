@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// runCleanupDrafts implements the cleanup-drafts command, which deletes draft
+// coding standards left behind by failed or unpromoted runs.
+func runCleanupDrafts(args []string) error {
+	fs := flag.NewFlagSet("cleanup-drafts", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		journals  = fs.String("journal", "", "Comma-separated run journals; drafts created by these runs are known to come from this tool")
+		ownOnly   = fs.Bool("created-by-tool", false, "Only consider drafts recorded in the given journals")
+		olderThan = fs.Duration("older-than", 0, "Only consider drafts created longer ago than this (e.g. 72h); requires --journal")
+		showDiff  = fs.Bool("diff", true, "Show each draft's differences versus its live standard")
+		yes       = fs.Bool("yes", false, "Delete without asking for confirmation")
+		dryRun    = fs.Bool("dry-run", false, "List the drafts that would be deleted without deleting them")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler cleanup-drafts [flags]
+
+Lists draft coding standards, shows how each differs from the live standard
+it was created from, and deletes them after confirmation.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if (*ownOnly || *olderThan > 0) && *journals == "" {
+		fs.Usage()
+		return fmt.Errorf("--created-by-tool and --older-than need --journal")
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+
+	// Drafts created by this tool, with their creation time.
+	created := make(map[int64]time.Time)
+	if *journals != "" {
+		for _, path := range strings.Split(*journals, ",") {
+			err := readJournal(strings.TrimSpace(path), func(e journalEntry) error {
+				if e.Step == stepDraftCreated {
+					created[e.DraftID] = e.Time
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+	client := conn.client(token)
+	all, err := client.ListCodingStandards(provider, orgName)
+	if err != nil {
+		return err
+	}
+
+	var drafts []codacy.CodingStandard
+	for _, cs := range all {
+		if !cs.IsDraft {
+			continue
+		}
+		createdAt, known := created[cs.ID]
+		if *ownOnly && !known {
+			continue
+		}
+		// The API does not report when a draft was created, so age is only
+		// known for drafts recorded in a journal.
+		if *olderThan > 0 && (!known || time.Since(createdAt) < *olderThan) {
+			continue
+		}
+		drafts = append(drafts, cs)
+	}
+
+	if len(drafts) == 0 {
+		fmt.Println("No matching draft coding standards found.")
+		return nil
+	}
+
+	fmt.Printf("Found %d draft coding standard(s):\n", len(drafts))
+	for _, d := range drafts {
+		fmt.Printf("  [%d] %s", d.ID, d.Name)
+		if createdAt, ok := created[d.ID]; ok {
+			fmt.Printf("  (created by this tool %s)", createdAt.Local().Format(time.RFC3339))
+		}
+		fmt.Println()
+		if !*showDiff {
+			continue
+		}
+		src, ok := sourceStandard(d, all)
+		if !ok {
+			fmt.Println("      No live standard with the same name and languages")
+			continue
+		}
+		diff, err := diffStandards(client, provider, orgName, src.ID, d.ID)
+		if err != nil {
+			logger.Warn("Could not compare draft with its live standard", keyDraftID, d.ID, keyStandardID, src.ID, "error", err)
+			continue
+		}
+		fmt.Printf("      Differences versus live standard %d:\n", src.ID)
+		printDiff(os.Stdout, diff, "        ")
+	}
+	fmt.Println()
+
+	if *dryRun {
+		fmt.Printf("[dry-run] would delete %d draft(s)\n", len(drafts))
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Delete %d draft(s)?", len(drafts))) {
+		fmt.Println("Aborted — no drafts deleted.")
+		return nil
+	}
+
+	var failed int
+	for _, d := range drafts {
+		if err := client.DeleteCodingStandard(provider, orgName, d.ID); err != nil {
+			logger.Warn("Could not delete draft", keyDraftID, d.ID, "error", err)
+			failed++
+			continue
+		}
+		fmt.Printf("Deleted draft %q (ID %d)\n", d.Name, d.ID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d draft(s) could not be deleted", failed, len(drafts))
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package codacy
 import (
 	"fmt"
 	"net/url"
+	"strings"
 )
 
 // ListCodingStandards returns all coding standards (draft and effective) for an
@@ -26,6 +27,26 @@ func (c *Client) GetCodingStandard(provider, orgName string, id int64) (*CodingS
 	return &resp.Data, nil
 }
 
+// CreateCodingStandard creates a new, empty draft coding standard for the
+// given languages.
+func (c *Client) CreateCodingStandard(provider, orgName, name string, languages []string) (*CodingStandard, error) {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards", provider, orgName)
+	body := CreateCodingStandardBody{
+		Name:      name,
+		Languages: languages,
+	}
+	var resp CodingStandardResponse
+	err := c.do("POST", path, nil, body, &resp)
+	c.mutated(Mutation{
+		Action: ActionCreateStandard, Provider: provider, Organization: orgName,
+		Target: MutationTarget{CodingStandardID: resp.Data.ID, Name: name},
+	}, err)
+	if err != nil {
+		return nil, fmt.Errorf("createCodingStandard(%s): %w", name, err)
+	}
+	return &resp.Data, nil
+}
+
 // CreateDraftFromStandard creates a new draft coding standard using an existing
 // standard as a source (copies its enabled repositories and default status).
 // The new draft gets the same name and languages as the source standard.
@@ -41,7 +62,12 @@ func (c *Client) CreateDraftFromStandard(provider, orgName string, source Coding
 	}
 
 	var resp CodingStandardResponse
-	if err := c.do("POST", path, query, body, &resp); err != nil {
+	err := c.do("POST", path, query, body, &resp)
+	c.mutated(Mutation{
+		Action: ActionCreateDraft, Provider: provider, Organization: orgName,
+		Target: MutationTarget{CodingStandardID: resp.Data.ID, SourceCodingStandardID: source.ID, Name: source.Name},
+	}, err)
+	if err != nil {
 		return nil, fmt.Errorf("createDraftFromStandard(%d): %w", source.ID, err)
 	}
 	return &resp.Data, nil
@@ -57,19 +83,80 @@ func (c *Client) ListCodingStandardTools(provider, orgName string, csID int64) (
 	return resp.Data, nil
 }
 
+// ListCodingStandardPatterns returns every pattern of a tool in a coding
+// standard together with its configuration, following cursor-based pagination
+// automatically.
+func (c *Client) ListCodingStandardPatterns(provider, orgName string, csID int64, toolUUID string) ([]ConfiguredPattern, error) {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d/tools/%s/patterns",
+		provider, orgName, csID, toolUUID)
+	var all []ConfiguredPattern
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "1000")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var resp ConfiguredPatternsListResponse
+		if err := c.do("GET", path, query, nil, &resp); err != nil {
+			return nil, fmt.Errorf("listCodingStandardPatterns(cs=%d, tool=%s): %w", csID, toolUUID, err)
+		}
+		all = append(all, resp.Data...)
+		if resp.Pagination == nil || resp.Pagination.Cursor == "" {
+			break
+		}
+		cursor = resp.Pagination.Cursor
+	}
+	return all, nil
+}
+
+// UpdateCodingStandardTool enables or disables a tool in a draft coding
+// standard and configures the given patterns; other patterns keep their
+// configuration.
+func (c *Client) UpdateCodingStandardTool(provider, orgName string, csID int64, toolUUID string, enabled bool, patterns []PatternConfigurationBody) error {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d/tools/%s",
+		provider, orgName, csID, toolUUID)
+	if patterns == nil {
+		patterns = []PatternConfigurationBody{}
+	}
+	body := UpdateCodingStandardToolBody{Enabled: enabled, Patterns: patterns}
+	err := c.do("PATCH", path, nil, body, nil)
+	c.mutated(Mutation{
+		Action: ActionUpdateTool, Provider: provider, Organization: orgName,
+		Target:  MutationTarget{CodingStandardID: csID, ToolUUID: toolUUID},
+		Enabled: &enabled,
+	}, err)
+	if err != nil {
+		return fmt.Errorf("updateCodingStandardTool(cs=%d, tool=%s): %w", csID, toolUUID, err)
+	}
+	return nil
+}
+
 // UpdateSecurityPatterns bulk-enables or bulk-disables all Security-category
 // patterns for a specific tool inside a draft coding standard.
 func (c *Client) UpdateSecurityPatterns(provider, orgName string, csID int64, toolUUID string, enable bool) error {
+	return c.UpdateCategoryPatterns(provider, orgName, csID, toolUUID, []string{"Security"}, enable)
+}
+
+// UpdateCategoryPatterns bulk-enables or bulk-disables all patterns in the
+// given categories for a specific tool inside a draft coding standard.
+func (c *Client) UpdateCategoryPatterns(provider, orgName string, csID int64, toolUUID string, categories []string, enable bool) error {
 	path := fmt.Sprintf(
 		"/organizations/%s/%s/coding-standards/%d/tools/%s/patterns/update",
 		provider, orgName, csID, toolUUID,
 	)
 	query := url.Values{}
-	query.Set("categories", "Security")
+	query.Set("categories", strings.Join(categories, ","))
 
 	body := UpdatePatternsBody{Enabled: enable}
-	if err := c.do("POST", path, query, body, nil); err != nil {
-		return fmt.Errorf("updateSecurityPatterns(cs=%d, tool=%s): %w", csID, toolUUID, err)
+	err := c.do("POST", path, query, body, nil)
+	c.mutated(Mutation{
+		Action: ActionUpdatePatterns, Provider: provider, Organization: orgName,
+		Target:     MutationTarget{CodingStandardID: csID, ToolUUID: toolUUID},
+		Categories: categories, Enabled: &enable,
+	}, err)
+	if err != nil {
+		return fmt.Errorf("updateCategoryPatterns(cs=%d, tool=%s): %w", csID, toolUUID, err)
 	}
 	return nil
 }
@@ -99,6 +186,30 @@ func (c *Client) ListRepositoriesWithAnalysis(provider, orgName string) ([]Repos
 	return all, nil
 }
 
+// ListTools returns every tool of the Codacy tools catalogue, including the
+// languages each tool supports, following cursor-based pagination automatically.
+func (c *Client) ListTools() ([]Tool, error) {
+	var all []Tool
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "100")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var resp ToolsListResponse
+		if err := c.do("GET", "/tools", query, nil, &resp); err != nil {
+			return nil, fmt.Errorf("listTools: %w", err)
+		}
+		all = append(all, resp.Data...)
+		if resp.Pagination == nil || resp.Pagination.Cursor == "" {
+			break
+		}
+		cursor = resp.Pagination.Cursor
+	}
+	return all, nil
+}
+
 // ListRepositoryTools returns the analysis tools configured for a repository.
 func (c *Client) ListRepositoryTools(provider, orgName, repoName string) ([]AnalysisTool, error) {
 	path := fmt.Sprintf("/analysis/organizations/%s/%s/repositories/%s/tools",
@@ -110,17 +221,56 @@ func (c *Client) ListRepositoryTools(provider, orgName, repoName string) ([]Anal
 	return resp.Data, nil
 }
 
+// ListRepositoryToolPatterns returns every pattern of a tool in a repository
+// together with its configuration, following cursor-based pagination
+// automatically.
+func (c *Client) ListRepositoryToolPatterns(provider, orgName, repoName, toolUUID string) ([]ConfiguredPattern, error) {
+	path := fmt.Sprintf("/analysis/organizations/%s/%s/repositories/%s/tools/%s/patterns",
+		provider, orgName, repoName, toolUUID)
+	var all []ConfiguredPattern
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "1000")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var resp ConfiguredPatternsListResponse
+		if err := c.do("GET", path, query, nil, &resp); err != nil {
+			return nil, fmt.Errorf("listRepositoryToolPatterns(repo=%s, tool=%s): %w", repoName, toolUUID, err)
+		}
+		all = append(all, resp.Data...)
+		if resp.Pagination == nil || resp.Pagination.Cursor == "" {
+			break
+		}
+		cursor = resp.Pagination.Cursor
+	}
+	return all, nil
+}
+
 // UpdateRepositorySecurityPatterns bulk-enables or bulk-disables all
 // Security-category patterns for a specific tool in a repository.
-// Uses PATCH /analysis/.../tools/{toolUuid}/patterns?categories=Security.
 func (c *Client) UpdateRepositorySecurityPatterns(provider, orgName, repoName, toolUUID string, enable bool) error {
+	return c.UpdateRepositoryCategoryPatterns(provider, orgName, repoName, toolUUID, []string{"Security"}, enable)
+}
+
+// UpdateRepositoryCategoryPatterns bulk-enables or bulk-disables all patterns
+// in the given categories for a specific tool in a repository.
+// Uses PATCH /analysis/.../tools/{toolUuid}/patterns?categories=....
+func (c *Client) UpdateRepositoryCategoryPatterns(provider, orgName, repoName, toolUUID string, categories []string, enable bool) error {
 	path := fmt.Sprintf("/analysis/organizations/%s/%s/repositories/%s/tools/%s/patterns",
 		provider, orgName, repoName, toolUUID)
 	query := url.Values{}
-	query.Set("categories", "Security")
+	query.Set("categories", strings.Join(categories, ","))
 	body := UpdatePatternsBody{Enabled: enable}
-	if err := c.do("PATCH", path, query, body, nil); err != nil {
-		return fmt.Errorf("updateRepositorySecurityPatterns(repo=%s, tool=%s): %w", repoName, toolUUID, err)
+	err := c.do("PATCH", path, query, body, nil)
+	c.mutated(Mutation{
+		Action: ActionUpdateRepositoryPatterns, Provider: provider, Organization: orgName,
+		Target:     MutationTarget{Repository: repoName, ToolUUID: toolUUID},
+		Categories: categories, Enabled: &enable,
+	}, err)
+	if err != nil {
+		return fmt.Errorf("updateRepositoryCategoryPatterns(repo=%s, tool=%s): %w", repoName, toolUUID, err)
 	}
 	return nil
 }
@@ -131,8 +281,114 @@ func (c *Client) UpdateRepositorySecurityPatterns(provider, orgName, repoName, t
 func (c *Client) PromoteDraftCodingStandard(provider, orgName string, csID int64) (*PromoteResult, error) {
 	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d/promote", provider, orgName, csID)
 	var resp PromoteResultResponse
-	if err := c.do("POST", path, nil, nil, &resp); err != nil {
+	err := c.do("POST", path, nil, nil, &resp)
+	c.mutated(Mutation{
+		Action: ActionPromote, Provider: provider, Organization: orgName,
+		Target: MutationTarget{CodingStandardID: csID},
+		Failed: resp.Data.Failed,
+	}, err)
+	if err != nil {
 		return nil, fmt.Errorf("promoteDraftCodingStandard(%d): %w", csID, err)
 	}
 	return &resp.Data, nil
 }
+
+// DeleteCodingStandard deletes a coding standard. Only drafts and standards
+// that are not the default can be deleted.
+func (c *Client) DeleteCodingStandard(provider, orgName string, id int64) error {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d", provider, orgName, id)
+	err := c.do("DELETE", path, nil, nil, nil)
+	c.mutated(Mutation{
+		Action: ActionDeleteStandard, Provider: provider, Organization: orgName,
+		Target: MutationTarget{CodingStandardID: id},
+	}, err)
+	if err != nil {
+		return fmt.Errorf("deleteCodingStandard(%d): %w", id, err)
+	}
+	return nil
+}
+
+// ApplyCodingStandardToRepositories links the repositories in link to a coding
+// standard and unlinks those in unlink. Linking a repository makes all of its
+// tools follow the standard.
+func (c *Client) ApplyCodingStandardToRepositories(provider, orgName string, csID int64, link, unlink []string) (*ApplyCodingStandardResult, error) {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d/repositories", provider, orgName, csID)
+	if link == nil {
+		link = []string{}
+	}
+	if unlink == nil {
+		unlink = []string{}
+	}
+	body := ApplyCodingStandardBody{Link: link, Unlink: unlink}
+	var resp ApplyCodingStandardResult
+	err := c.do("PATCH", path, nil, body, &resp)
+	c.mutated(Mutation{
+		Action: ActionApplyToRepositories, Provider: provider, Organization: orgName,
+		Target: MutationTarget{CodingStandardID: csID, Link: link, Unlink: unlink},
+		Failed: resp.Failed,
+	}, err)
+	if err != nil {
+		return nil, fmt.Errorf("applyCodingStandardToRepositories(%d): %w", csID, err)
+	}
+	return &resp, nil
+}
+
+// SetDefaultCodingStandard marks a coding standard as the default for new
+// repositories of the organisation.
+func (c *Client) SetDefaultCodingStandard(provider, orgName string, csID int64) error {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d/setDefault", provider, orgName, csID)
+	body := SetDefaultCodingStandardBody{IsDefault: true}
+	err := c.do("POST", path, nil, body, nil)
+	c.mutated(Mutation{
+		Action: ActionSetDefault, Provider: provider, Organization: orgName,
+		Target: MutationTarget{CodingStandardID: csID},
+	}, err)
+	if err != nil {
+		return fmt.Errorf("setDefaultCodingStandard(%d): %w", csID, err)
+	}
+	return nil
+}
+
+// ListCodingStandardRepositories returns the repositories linked to a coding
+// standard, following cursor-based pagination automatically.
+func (c *Client) ListCodingStandardRepositories(provider, orgName string, csID int64) ([]Repository, error) {
+	path := fmt.Sprintf("/organizations/%s/%s/coding-standards/%d/repositories", provider, orgName, csID)
+	var all []Repository
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "100")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var resp RepositoriesListResponse
+		if err := c.do("GET", path, query, nil, &resp); err != nil {
+			return nil, fmt.Errorf("listCodingStandardRepositories(%d): %w", csID, err)
+		}
+		all = append(all, resp.Data...)
+		if resp.Pagination == nil || resp.Pagination.Cursor == "" {
+			break
+		}
+		cursor = resp.Pagination.Cursor
+	}
+	return all, nil
+}
+
+// AddRepositoriesToCodingStandard links repositories to a coding standard.
+func (c *Client) AddRepositoriesToCodingStandard(provider, orgName string, csID int64, repos []string) (*ApplyCodingStandardResult, error) {
+	return c.ApplyCodingStandardToRepositories(provider, orgName, csID, repos, nil)
+}
+
+// RemoveRepositoriesFromCodingStandard unlinks repositories from a coding standard.
+func (c *Client) RemoveRepositoriesFromCodingStandard(provider, orgName string, csID int64, repos []string) (*ApplyCodingStandardResult, error) {
+	return c.ApplyCodingStandardToRepositories(provider, orgName, csID, nil, repos)
+}
+
+// GetUser returns the user the API token belongs to.
+func (c *Client) GetUser() (*User, error) {
+	var resp UserResponse
+	if err := c.do("GET", "/user", nil, nil, &resp); err != nil {
+		return nil, fmt.Errorf("getUser: %w", err)
+	}
+	return &resp.Data, nil
+}
@@ -0,0 +1,212 @@
+package codacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cassetteVersion is the version of the cassette file format.
+const cassetteVersion = 1
+
+// cassette is the file a Recorder writes and a Replayer reads: the HTTP
+// exchanges of a run in the order they happened.
+type cassette struct {
+	Version      int           `json:"version"`
+	RecordedAt   time.Time     `json:"recordedAt"`
+	Interactions []interaction `json:"interactions"`
+}
+
+// interaction is one recorded HTTP exchange. Credentials are never recorded.
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body"`
+}
+
+// WithTransport makes the client send its requests through rt, e.g. a
+// Recorder or a Replayer. Options that wrap the transport, such as
+// WithTracer, must come after it.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = rt
+	}
+}
+
+// Recorder is an http.RoundTripper that passes requests on and records every
+// exchange, for a Replayer to play back later. A Recorder may be shared by
+// several clients.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []interaction
+}
+
+// NewRecorder returns a Recorder sending requests through next, or through
+// http.DefaultTransport when next is nil.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	headers := resp.Header.Clone()
+	for _, name := range redactedHeaders {
+		headers.Del(name)
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction{
+		Request:  recordedRequest{Method: req.Method, URL: req.URL.String(), Body: string(reqBody)},
+		Response: recordedResponse{Status: resp.StatusCode, Headers: headers, Body: string(respBody)},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Save writes the exchanges recorded so far to the cassette file at path.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	c := cassette{Version: cassetteVersion, RecordedAt: time.Now().UTC(), Interactions: r.interactions}
+	r.mu.Unlock()
+	if c.Interactions == nil {
+		c.Interactions = []interaction{}
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette
+// without touching the network. A request is answered by the first unused
+// interaction with the same method, path, query and body, so requests that
+// were repeated get their responses in the order they were recorded.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []interaction
+	used         []bool
+}
+
+// LoadReplayer reads the cassette file at path.
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("decoding cassette %s: %w", path, err)
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d, want %d", path, c.Version, cassetteVersion)
+	}
+	return &Replayer{interactions: c.Interactions, used: make([]bool, len(c.Interactions))}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := replayKey(req.Method, req.URL.String(), string(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, it := range r.interactions {
+		if r.used[i] || replayKey(it.Request.Method, it.Request.URL, it.Request.Body) != key {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", it.Response.Status, http.StatusText(it.Response.Status)),
+			StatusCode:    it.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        it.Response.Headers.Clone(),
+			Body:          io.NopCloser(strings.NewReader(it.Response.Body)),
+			ContentLength: int64(len(it.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded response left for %s %s", req.Method, req.URL.RequestURI())
+}
+
+// Unused returns the number of recorded interactions that were not replayed.
+func (r *Replayer) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// replayKey identifies a request independently of the host it was sent to
+// and of the order of its query parameters.
+func replayKey(method, rawURL, body string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL + "\n" + body
+	}
+	return method + " " + u.Path + "?" + u.Query().Encode() + "\n" + body
+}
+
+// requestBody returns the body of req without consuming it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body of %s %s cannot be read twice", req.Method, req.URL)
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
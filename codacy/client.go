@@ -2,11 +2,13 @@ package codacy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -17,17 +19,65 @@ type Client struct {
 	baseURL    string
 	apiToken   string
 	httpClient *http.Client
+	ctx        context.Context
+	onMutation func(Mutation)
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL makes the client talk to the Codacy API at u instead of
+// app.codacy.com, e.g. a self-hosted installation.
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// WrapTransport makes the client send its requests through the transport
+// returned by wrap, which is given the transport used so far, e.g. to observe
+// every request. Options that replace the transport, such as WithTransport,
+// must come before it.
+func WrapTransport(wrap func(next http.RoundTripper) http.RoundTripper) Option {
+	return func(c *Client) {
+		next := c.httpClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.httpClient.Transport = wrap(next)
+	}
 }
 
 // NewClient returns a Client that authenticates with apiToken.
-func NewClient(apiToken string) *Client {
-	return &Client{
+func NewClient(apiToken string, opts ...Option) *Client {
+	c := &Client{
 		baseURL:  baseURL,
 		apiToken: apiToken,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithContext returns a shallow copy of c whose requests are made with ctx,
+// e.g. to cancel them or to make them children of the span ctx carries.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
+
+// Context returns the context of the requests of c, which is
+// context.Background unless set by WithContext.
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
 
 // do executes an HTTP request and, when result is non-nil, JSON-decodes the
@@ -47,7 +97,7 @@ func (c *Client) do(method, path string, query url.Values, body, result interfac
 		reqBody = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequest(method, endpoint, reqBody)
+	req, err := http.NewRequestWithContext(c.Context(), method, endpoint, reqBody)
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
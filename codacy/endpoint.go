@@ -0,0 +1,46 @@
+package codacy
+
+import (
+	"slices"
+	"strings"
+)
+
+// endpointParams gives, for each segment of an API path that is followed by
+// parameters, the names of those parameters.
+var endpointParams = map[string][]string{
+	"organizations":    {"{provider}", "{organization}"},
+	"coding-standards": {"{codingStandardId}"},
+	"repositories":     {"{repositoryName}"},
+	"tools":            {"{toolUuid}"},
+}
+
+// endpointRoots are the first segments of the API paths, after the base path.
+var endpointRoots = []string{"analysis", "organizations", "tools", "user"}
+
+// Endpoint returns the route of the API request path p, without the base path
+// of the API and with its parameters replaced by their names, e.g.
+// /organizations/{provider}/{organization}/coding-standards/{codingStandardId}.
+// Routes identify endpoints in metrics and traces without the cardinality of
+// raw paths.
+func Endpoint(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	start := len(segments)
+	for i, s := range segments {
+		if slices.Contains(endpointRoots, s) {
+			start = i
+			break
+		}
+	}
+	var route []string
+	for i := start; i < len(segments); i++ {
+		route = append(route, segments[i])
+		for _, name := range endpointParams[segments[i]] {
+			if i+1 == len(segments) {
+				break
+			}
+			i++
+			route = append(route, name)
+		}
+	}
+	return "/" + strings.Join(route, "/")
+}
@@ -39,6 +39,33 @@ type CodingStandardToolsListResponse struct {
 	Data []CodingStandardTool `json:"data"`
 }
 
+// PatternDefinition describes a code pattern provided by a tool.
+type PatternDefinition struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Level    string `json:"level"`
+}
+
+// PatternParameter is a named parameter value of a configured pattern.
+type PatternParameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ConfiguredPattern is a pattern together with its configuration in a coding standard.
+type ConfiguredPattern struct {
+	PatternDefinition PatternDefinition  `json:"patternDefinition"`
+	Enabled           bool               `json:"enabled"`
+	Parameters        []PatternParameter `json:"parameters"`
+}
+
+// ConfiguredPatternsListResponse wraps the paginated list of ConfiguredPattern values.
+type ConfiguredPatternsListResponse struct {
+	Data       []ConfiguredPattern `json:"data"`
+	Pagination *PaginationInfo     `json:"pagination,omitempty"`
+}
+
 // CodingStandardInfo is a lightweight reference to a coding standard,
 // used as an element of Repository.Standards and AnalysisToolSettings.EnabledBy.
 type CodingStandardInfo struct {
@@ -46,12 +73,19 @@ type CodingStandardInfo struct {
 	Name string `json:"name"`
 }
 
-// Repository holds the identity and associated coding standards for a repository.
+// Repository holds the identity, languages and associated coding standards for a repository.
 type Repository struct {
 	Name      string               `json:"name"`
+	Languages []string             `json:"languages"`
 	Standards []CodingStandardInfo `json:"standards"`
 }
 
+// RepositoriesListResponse wraps the paginated list of Repository values.
+type RepositoriesListResponse struct {
+	Data       []Repository    `json:"data"`
+	Pagination *PaginationInfo `json:"pagination,omitempty"`
+}
+
 // RepositoryWithAnalysis is one item from listOrganizationRepositoriesWithAnalysis.
 type RepositoryWithAnalysis struct {
 	Repository Repository `json:"repository"`
@@ -84,6 +118,20 @@ type AnalysisTool struct {
 	Settings AnalysisToolSettings `json:"settings"`
 }
 
+// Tool is a tool definition from the Codacy tools catalogue.
+type Tool struct {
+	UUID      string   `json:"uuid"`
+	Name      string   `json:"name"`
+	ShortName string   `json:"shortName"`
+	Languages []string `json:"languages"`
+}
+
+// ToolsListResponse wraps the paginated list of Tool values.
+type ToolsListResponse struct {
+	Data       []Tool          `json:"data"`
+	Pagination *PaginationInfo `json:"pagination,omitempty"`
+}
+
 // AnalysisToolsListResponse wraps a list of AnalysisTool values.
 type AnalysisToolsListResponse struct {
 	Data []AnalysisTool `json:"data"`
@@ -95,6 +143,26 @@ type CreateCodingStandardBody struct {
 	Languages []string `json:"languages"`
 }
 
+// PatternConfigurationBody sets the state and parameters of one pattern.
+type PatternConfigurationBody struct {
+	ID         string             `json:"id"`
+	Enabled    bool               `json:"enabled"`
+	Parameters []PatternParameter `json:"parameters,omitempty"`
+}
+
+// UpdateCodingStandardToolBody is the request body for configuring a tool in a
+// coding standard. Patterns not listed keep their configuration.
+type UpdateCodingStandardToolBody struct {
+	Enabled  bool                       `json:"enabled"`
+	Patterns []PatternConfigurationBody `json:"patterns"`
+}
+
+// SetDefaultCodingStandardBody is the request body for marking a coding
+// standard as the default for new repositories.
+type SetDefaultCodingStandardBody struct {
+	IsDefault bool `json:"isDefault"`
+}
+
 // UpdatePatternsBody is the request body for the bulk-update patterns endpoint.
 type UpdatePatternsBody struct {
 	Enabled bool `json:"enabled"`
@@ -110,3 +178,29 @@ type PromoteResult struct {
 type PromoteResultResponse struct {
 	Data PromoteResult `json:"data"`
 }
+
+// ApplyCodingStandardBody is the request body for linking repositories to, or
+// unlinking them from, a coding standard.
+type ApplyCodingStandardBody struct {
+	Link   []string `json:"link"`
+	Unlink []string `json:"unlink"`
+}
+
+// ApplyCodingStandardResult holds the repositories a coding standard was (or
+// could not be) linked to or unlinked from.
+type ApplyCodingStandardResult struct {
+	Successful []string `json:"successful"`
+	Failed     []string `json:"failed"`
+}
+
+// User is the Codacy user an API token belongs to.
+type User struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	MainEmail string `json:"mainEmail"`
+}
+
+// UserResponse wraps the User returned by getUser.
+type UserResponse struct {
+	Data User `json:"data"`
+}
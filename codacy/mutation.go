@@ -0,0 +1,60 @@
+package codacy
+
+// Actions of the mutations reported to the hook installed by WithMutationHook.
+const (
+	ActionCreateStandard           = "createCodingStandard"
+	ActionCreateDraft              = "createDraft"
+	ActionUpdateTool               = "updateTool"
+	ActionUpdatePatterns           = "updatePatterns"
+	ActionUpdateRepositoryPatterns = "updateRepositoryPatterns"
+	ActionPromote                  = "promote"
+	ActionDeleteStandard           = "deleteCodingStandard"
+	ActionApplyToRepositories      = "applyToRepositories"
+	ActionSetDefault               = "setDefault"
+)
+
+// Mutation describes an API call that changed, or tried to change, the
+// configuration of an organisation.
+type Mutation struct {
+	Action       string
+	Provider     string
+	Organization string
+	Target       MutationTarget
+	Categories   []string // patterns updated by category
+	Enabled      *bool    // state the tool or patterns were set to
+	// Failed lists the repositories a promoted or applied standard could
+	// not be applied to.
+	Failed []string
+	Err    error // nil when the call succeeded
+}
+
+// MutationTarget identifies what a Mutation changed.
+type MutationTarget struct {
+	CodingStandardID int64 `json:"codingStandardId,omitempty"`
+	// SourceCodingStandardID is the standard a draft was created from.
+	SourceCodingStandardID int64    `json:"sourceCodingStandardId,omitempty"`
+	Name                   string   `json:"name,omitempty"`
+	Repository             string   `json:"repository,omitempty"`
+	ToolUUID               string   `json:"toolUuid,omitempty"`
+	Link                   []string `json:"link,omitempty"`
+	Unlink                 []string `json:"unlink,omitempty"`
+}
+
+// WithMutationHook makes the client call fn after every API call that changes
+// the configuration of an organisation, whether it succeeded or not. fn may
+// be called concurrently and may use the client for read-only calls.
+func WithMutationHook(fn func(Mutation)) Option {
+	return func(c *Client) {
+		c.onMutation = fn
+	}
+}
+
+// mutated reports m, the outcome of a mutating call that returned err, to the
+// mutation hook.
+func (c *Client) mutated(m Mutation, err error) {
+	if c.onMutation == nil {
+		return
+	}
+	m.Err = err
+	c.onMutation(m)
+}
@@ -0,0 +1,257 @@
+package codacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// traceBodyLimit is the number of bytes of a request or response body logged
+// by a Tracer. HAR entries keep the whole body.
+const traceBodyLimit = 2000
+
+// redactedHeaders are the headers whose values a Tracer never records.
+var redactedHeaders = []string{"Api-Token", "Authorization", "Cookie", "Set-Cookie"}
+
+// Tracer records the HTTP exchanges of a Client for debugging. Each exchange
+// is logged, and kept for WriteHAR when the Tracer was created to keep them.
+// A Tracer may be shared by several clients.
+type Tracer struct {
+	logger *slog.Logger // nil: do not log
+	keep   bool
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewTracer returns a Tracer logging to logger, which may be nil, and keeping
+// the exchanges for WriteHAR when keep is set.
+func NewTracer(logger *slog.Logger, keep bool) *Tracer {
+	return &Tracer{logger: logger, keep: keep}
+}
+
+// WithTracer makes the client report its HTTP exchanges to t.
+func WithTracer(t *Tracer) Option {
+	return WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		return &tracingTransport{next: next, tracer: t}
+	})
+}
+
+// tracingTransport passes requests on to next and reports each exchange to tracer.
+type tracingTransport struct {
+	next   http.RoundTripper
+	tracer *Tracer
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, _ := requestBody(req)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	var respBody []byte
+	if err == nil {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			resp = nil
+		} else {
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+		}
+	}
+	t.tracer.record(req, reqBody, resp, respBody, start, time.Since(start), err)
+	return resp, err
+}
+
+// record logs one exchange and keeps it when t keeps exchanges. resp is nil
+// when the request failed with err.
+func (t *Tracer) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, start time.Time, latency time.Duration, err error) {
+	u := *req.URL
+	u.RawQuery = ""
+	if t.logger != nil {
+		attrs := []any{
+			"method", req.Method,
+			"url", u.String(),
+			"query", req.URL.RawQuery,
+			"request_headers", headerMap(req.Header),
+			"request_body", truncate(string(reqBody), traceBodyLimit),
+			"latency", latency,
+		}
+		if err != nil {
+			t.logger.Warn("HTTP request failed", append(attrs, "error", err)...)
+		} else {
+			t.logger.Info("HTTP exchange", append(attrs,
+				"status", resp.StatusCode,
+				"response_body", truncate(string(respBody), traceBodyLimit))...)
+		}
+	}
+	if !t.keep {
+		return
+	}
+
+	e := harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            latency.Seconds() * 1000,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Headers:     []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Cache:   struct{}{},
+		Timings: harTimings{Send: 0, Wait: latency.Seconds() * 1000, Receive: 0},
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: name, Value: v})
+		}
+	}
+	if len(reqBody) > 0 {
+		e.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(reqBody)}
+	}
+	if err != nil {
+		e.Comment = err.Error()
+	} else {
+		e.Response.Status = resp.StatusCode
+		e.Response.StatusText = http.StatusText(resp.StatusCode)
+		e.Response.HTTPVersion = resp.Proto
+		e.Response.Headers = harHeaders(resp.Header)
+		e.Response.BodySize = len(respBody)
+		e.Response.Content = harContent{
+			Size:     len(respBody),
+			MimeType: resp.Header.Get("Content-Type"),
+			Text:     string(respBody),
+		}
+	}
+
+	t.mu.Lock()
+	t.entries = append(t.entries, e)
+	t.mu.Unlock()
+}
+
+// WriteHAR writes the exchanges kept so far to w as an HTTP Archive (HAR 1.2)
+// document, which browsers' developer tools and most HTTP debuggers can open.
+func (t *Tracer) WriteHAR(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "codacy-security-toggler", Version: "1"},
+		Entries: t.entries,
+	}}
+	if doc.Log.Entries == nil {
+		doc.Log.Entries = []harEntry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// headerMap returns the headers of h as a map for logging, with the values
+// of credentials masked.
+func headerMap(h http.Header) map[string]string {
+	m := make(map[string]string, len(h))
+	for name, values := range h {
+		m[name] = redactHeader(name, strings.Join(values, ", "))
+	}
+	return m
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			headers = append(headers, harNameValue{Name: name, Value: redactHeader(name, v)})
+		}
+	}
+	return headers
+}
+
+func redactHeader(name, value string) string {
+	for _, r := range redactedHeaders {
+		if strings.EqualFold(name, r) {
+			return "REDACTED"
+		}
+	}
+	return value
+}
+
+// The subset of the HAR 1.2 format written by WriteHAR.
+type (
+	harDocument struct {
+		Log harLog `json:"log"`
+	}
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		Cookies     []harNameValue `json:"cookies"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		Cookies     []harNameValue `json:"cookies"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+	}
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the YAML configuration file holding named profiles.
+type configFile struct {
+	// DefaultProfile is used when no profile is selected. Without it, a
+	// profile named "default" is used if there is one.
+	DefaultProfile string             `yaml:"default-profile"`
+	Profiles       map[string]profile `yaml:"profiles"`
+}
+
+// profile holds the settings of one named profile. Every setting corresponds
+// to the command-line flag of the same name.
+type profile struct {
+	Provider     string   `yaml:"provider"`
+	Organization string   `yaml:"organization"`
+	APIURL       string   `yaml:"api-url"`
+	Standards    []string `yaml:"standards"`
+	Repositories []string `yaml:"repositories"`
+	Categories   []string `yaml:"categories"`
+	Concurrency  int      `yaml:"concurrency"`
+	LogLevel     string   `yaml:"log-level"`
+	LogFormat    string   `yaml:"log-format"`
+	AuditLog     string   `yaml:"audit-log"`
+}
+
+// configSettings maps the flags that can be set from a profile to the
+// environment variables that override the profile.
+var configSettings = []struct{ flag, env string }{
+	{"provider", "CODACY_PROVIDER"},
+	{"organization", "CODACY_ORGANIZATION"},
+	{"api-url", "CODACY_API_URL"},
+	{"standards", "CODACY_STANDARDS"},
+	{"repositories", "CODACY_REPOSITORIES"},
+	{"categories", "CODACY_CATEGORIES"},
+	{"concurrency", "CODACY_CONCURRENCY"},
+	{"log-level", "CODACY_LOG_LEVEL"},
+	{"log-format", "CODACY_LOG_FORMAT"},
+	{"audit-log", "CODACY_AUDIT_LOG"},
+}
+
+// values returns the settings of p as flag values, keyed by flag name. Unset
+// settings are omitted.
+func (p profile) values() map[string]string {
+	v := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			v[name] = value
+		}
+	}
+	set("provider", p.Provider)
+	set("organization", p.Organization)
+	set("api-url", p.APIURL)
+	set("standards", strings.Join(p.Standards, ","))
+	set("repositories", strings.Join(p.Repositories, ","))
+	set("categories", strings.Join(p.Categories, ","))
+	if p.Concurrency != 0 {
+		set("concurrency", strconv.Itoa(p.Concurrency))
+	}
+	set("log-level", p.LogLevel)
+	set("log-format", p.LogFormat)
+	set("audit-log", p.AuditLog)
+	return v
+}
+
+// defaultConfigPath returns the configuration file used when neither --config
+// nor CODACY_CONFIG is given.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "codacy-security-toggler", "config.yaml")
+}
+
+// loadProfile reads the configuration file at path and returns the profile
+// named name, or the default profile when name is empty. A missing file is
+// only an error when it was asked for explicitly.
+func loadProfile(path, name string, explicit bool) (profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			if name != "" {
+				return profile{}, fmt.Errorf("profile %q requested but no configuration file found at %s", name, path)
+			}
+			return profile{}, nil
+		}
+		return profile{}, fmt.Errorf("reading configuration: %w", err)
+	}
+	var cfg configFile
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return profile{}, fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	if name == "" {
+		return cfg.Profiles["default"], nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for n := range cfg.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return profile{}, fmt.Errorf("%s has no profile %q (profiles: %s)", path, name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// applyConfig fills in the flags of fs that were not given on the command line,
+// first from the environment and then from the selected profile of the
+// configuration file, so that flags take precedence over the environment and
+// the environment over the file. Settings fs has no flag for are ignored.
+func applyConfig(fs *flag.FlagSet, configPath, profileName string) error {
+	explicit := configPath != ""
+	if !explicit {
+		configPath = os.Getenv("CODACY_CONFIG")
+		explicit = configPath != ""
+	}
+	if !explicit {
+		configPath = defaultConfigPath()
+	}
+	if profileName == "" {
+		profileName = os.Getenv("CODACY_PROFILE")
+	}
+
+	var fromFile map[string]string
+	if configPath != "" {
+		p, err := loadProfile(configPath, profileName, explicit)
+		if err != nil {
+			return err
+		}
+		fromFile = p.values()
+	} else if profileName != "" {
+		return fmt.Errorf("profile %q requested but no configuration file location is known — use --config", profileName)
+	}
+
+	onCommandLine := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+	for _, s := range configSettings {
+		if onCommandLine[s.flag] || fs.Lookup(s.flag) == nil {
+			continue
+		}
+		value, source := os.Getenv(s.env), s.env
+		if value == "" {
+			value, source = fromFile[s.flag], "profile"
+		}
+		if value == "" {
+			continue
+		}
+		if err := fs.Set(s.flag, value); err != nil {
+			return fmt.Errorf("invalid %s from %s: %w", s.flag, source, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// standardDiff lists the tool and pattern differences between two coding standards.
+type standardDiff struct {
+	FromID int64      `json:"fromId"`
+	ToID   int64      `json:"toId"`
+	Tools  []toolDiff `json:"tools"`
+}
+
+// toolDiff describes how one tool differs between two coding standards. A tool
+// missing from a standard counts as disabled there.
+type toolDiff struct {
+	UUID        string        `json:"uuid"`
+	FromEnabled bool          `json:"fromEnabled"`
+	ToEnabled   bool          `json:"toEnabled"`
+	Patterns    []patternDiff `json:"patterns,omitempty"`
+}
+
+// patternDiff describes how one pattern differs between two coding standards.
+// Parameters are only set when they differ.
+type patternDiff struct {
+	ID             string            `json:"id"`
+	Category       string            `json:"category"`
+	FromEnabled    bool              `json:"fromEnabled"`
+	ToEnabled      bool              `json:"toEnabled"`
+	FromParameters map[string]string `json:"fromParameters,omitempty"`
+	ToParameters   map[string]string `json:"toParameters,omitempty"`
+}
+
+// diffStandards compares the coding standards fromID and toID tool by tool and
+// pattern by pattern. Patterns are only compared for tools enabled in at least
+// one of the two standards.
+func diffStandards(client *codacy.Client, provider, orgName string, fromID, toID int64) (*standardDiff, error) {
+	fromTools, err := client.ListCodingStandardTools(provider, orgName, fromID)
+	if err != nil {
+		return nil, err
+	}
+	toTools, err := client.ListCodingStandardTools(provider, orgName, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromEnabled := make(map[string]bool)
+	for _, t := range fromTools {
+		fromEnabled[t.UUID] = t.IsEnabled
+	}
+	toEnabled := make(map[string]bool)
+	for _, t := range toTools {
+		toEnabled[t.UUID] = t.IsEnabled
+	}
+	uuids := unionKeys(fromEnabled, toEnabled)
+
+	d := &standardDiff{FromID: fromID, ToID: toID}
+	for _, uuid := range uuids {
+		td := toolDiff{UUID: uuid, FromEnabled: fromEnabled[uuid], ToEnabled: toEnabled[uuid]}
+		if td.FromEnabled || td.ToEnabled {
+			var fromPatterns, toPatterns []codacy.ConfiguredPattern
+			if _, ok := fromEnabled[uuid]; ok {
+				if fromPatterns, err = client.ListCodingStandardPatterns(provider, orgName, fromID, uuid); err != nil {
+					return nil, err
+				}
+			}
+			if _, ok := toEnabled[uuid]; ok {
+				if toPatterns, err = client.ListCodingStandardPatterns(provider, orgName, toID, uuid); err != nil {
+					return nil, err
+				}
+			}
+			td.Patterns = diffPatterns(fromPatterns, toPatterns)
+		}
+		if td.FromEnabled != td.ToEnabled || len(td.Patterns) > 0 {
+			d.Tools = append(d.Tools, td)
+		}
+	}
+	return d, nil
+}
+
+// diffPatterns returns the patterns whose enabled state or parameters differ.
+func diffPatterns(from, to []codacy.ConfiguredPattern) []patternDiff {
+	fromByID := make(map[string]codacy.ConfiguredPattern, len(from))
+	for _, p := range from {
+		fromByID[p.PatternDefinition.ID] = p
+	}
+	toByID := make(map[string]codacy.ConfiguredPattern, len(to))
+	for _, p := range to {
+		toByID[p.PatternDefinition.ID] = p
+	}
+	var diffs []patternDiff
+	for _, id := range unionKeys(fromByID, toByID) {
+		f, t := fromByID[id], toByID[id]
+		category := f.PatternDefinition.Category
+		if category == "" {
+			category = t.PatternDefinition.Category
+		}
+		pd := patternDiff{ID: id, Category: category, FromEnabled: f.Enabled, ToEnabled: t.Enabled}
+		fp, tp := parameterMap(f.Parameters), parameterMap(t.Parameters)
+		paramsDiffer := !maps.Equal(fp, tp)
+		if paramsDiffer {
+			pd.FromParameters, pd.ToParameters = fp, tp
+		}
+		if pd.FromEnabled != pd.ToEnabled || paramsDiffer {
+			diffs = append(diffs, pd)
+		}
+	}
+	return diffs
+}
+
+func parameterMap(params []codacy.PatternParameter) map[string]string {
+	m := make(map[string]string, len(params))
+	for _, p := range params {
+		m[p.Name] = p.Value
+	}
+	return m
+}
+
+// filterCategories returns a copy of d keeping only the patterns in one of
+// categories (case-insensitive). Tools whose enabled state differs are always
+// kept. An empty categories list keeps everything.
+func (d *standardDiff) filterCategories(categories []string) *standardDiff {
+	if len(categories) == 0 {
+		return d
+	}
+	out := &standardDiff{FromID: d.FromID, ToID: d.ToID}
+	for _, td := range d.Tools {
+		filtered := td
+		filtered.Patterns = nil
+		for _, pd := range td.Patterns {
+			if containsFold(categories, pd.Category) {
+				filtered.Patterns = append(filtered.Patterns, pd)
+			}
+		}
+		if filtered.FromEnabled != filtered.ToEnabled || len(filtered.Patterns) > 0 {
+			out.Tools = append(out.Tools, filtered)
+		}
+	}
+	return out
+}
+
+// printDiff writes a human-readable rendering of d to w, indenting every line
+// with indent.
+func printDiff(w io.Writer, d *standardDiff, indent string) {
+	if len(d.Tools) == 0 {
+		fmt.Fprintf(w, "%sNo differences\n", indent)
+		return
+	}
+	for _, td := range d.Tools {
+		if td.FromEnabled != td.ToEnabled {
+			fmt.Fprintf(w, "%sTool %s: %s (was %s)\n", indent, td.UUID, enabledWord(td.ToEnabled), enabledWord(td.FromEnabled))
+		} else {
+			fmt.Fprintf(w, "%sTool %s: %s\n", indent, td.UUID, enabledWord(td.ToEnabled))
+		}
+		for _, pd := range td.Patterns {
+			mark := " "
+			switch {
+			case pd.ToEnabled && !pd.FromEnabled:
+				mark = "+"
+			case !pd.ToEnabled && pd.FromEnabled:
+				mark = "-"
+			}
+			fmt.Fprintf(w, "%s  %s %s [%s]\n", indent, mark, pd.ID, pd.Category)
+			for _, name := range unionKeys(pd.FromParameters, pd.ToParameters) {
+				from, fromSet := pd.FromParameters[name]
+				to, toSet := pd.ToParameters[name]
+				switch {
+				case !toSet:
+					fmt.Fprintf(w, "%s      %s: %q -> (unset)\n", indent, name, from)
+				case !fromSet:
+					fmt.Fprintf(w, "%s      %s: (unset) -> %q\n", indent, name, to)
+				case from != to:
+					fmt.Fprintf(w, "%s      %s: %q -> %q\n", indent, name, from, to)
+				}
+			}
+		}
+	}
+}
+
+// unionKeys returns the keys present in a or b, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	return unionKeys(m, nil)
+}
+
+func enabledWord(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// runDiff implements the diff command, which compares two coding standards
+// tool by tool and pattern by pattern.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		fromID     = fs.Int64("from", 0, "ID of the coding standard to compare from")
+		toID       = fs.Int64("to", 0, "ID of the coding standard to compare to")
+		draftID    = fs.Int64("draft", 0, "ID of a draft to compare with the live standard it was created from (instead of --from/--to)")
+		categories = fs.String("category", "", "Comma-separated pattern categories to show, e.g. Security (default: all)")
+		format     = fs.String("format", "text", "Output format: text or json")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler diff [flags]
+
+Shows the per-tool, per-pattern differences in enabled state and parameters
+between two coding standards, or between a draft and its live standard.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if err := validFormat(*format); err != nil {
+		return err
+	}
+	byDraft, byPair := *draftID != 0, *fromID != 0 || *toID != 0
+	if byDraft == byPair || (byPair && (*fromID == 0 || *toID == 0)) {
+		fs.Usage()
+		return fmt.Errorf("give either --draft, or both --from and --to")
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+	client := conn.client(token)
+
+	from, to := *fromID, *toID
+	if *draftID != 0 {
+		all, err := client.ListCodingStandards(provider, orgName)
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(all, func(cs codacy.CodingStandard) bool { return cs.ID == *draftID })
+		if i < 0 || !all[i].IsDraft {
+			return fmt.Errorf("coding standard %d is not a draft", *draftID)
+		}
+		src, ok := sourceStandard(all[i], all)
+		if !ok {
+			return fmt.Errorf("no live standard with the same name and languages as draft %d", *draftID)
+		}
+		from, to = src.ID, *draftID
+	}
+
+	d, err := diffStandards(client, provider, orgName, from, to)
+	if err != nil {
+		return err
+	}
+	d = d.filterCategories(splitList(*categories))
+
+	if *format == "json" {
+		if d.Tools == nil {
+			d.Tools = []toolDiff{}
+		}
+		return printJSON(d)
+	}
+	fmt.Printf("Differences from coding standard %d to %d:\n", from, to)
+	printDiff(os.Stdout, d, "  ")
+	return nil
+}
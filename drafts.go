@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// Draft strategies applied when a standard that is not a draft must be edited.
+const (
+	draftReuse   = "reuse"   // keep editing a draft already created from the standard
+	draftReplace = "replace" // delete existing drafts of the standard and create a new one
+	draftNew     = "new"     // always create a new draft
+)
+
+func validDraftStrategy(s string) bool {
+	return s == draftReuse || s == draftReplace || s == draftNew
+}
+
+// linkedDrafts returns the drafts in all that were created from the effective
+// standard cs, newest first. The API does not expose the source of a draft, so
+// a draft is considered linked when it has the same name and languages —
+// which is what CreateDraftFromStandard produces.
+func linkedDrafts(cs codacy.CodingStandard, all []codacy.CodingStandard) []codacy.CodingStandard {
+	var drafts []codacy.CodingStandard
+	for _, d := range all {
+		if d.IsDraft && d.ID != cs.ID && d.Name == cs.Name && sameLanguages(d.Languages, cs.Languages) {
+			drafts = append(drafts, d)
+		}
+	}
+	slices.SortFunc(drafts, func(a, b codacy.CodingStandard) int {
+		switch {
+		case a.ID > b.ID:
+			return -1
+		case a.ID < b.ID:
+			return 1
+		}
+		return 0
+	})
+	return drafts
+}
+
+func sameLanguages(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// withoutLinkedDrafts drops from selected the drafts that will be handled
+// through their effective standard, so that they are not processed twice.
+func withoutLinkedDrafts(selected, all []codacy.CodingStandard) []codacy.CodingStandard {
+	linked := make(map[int64]bool)
+	for _, cs := range selected {
+		if cs.IsDraft {
+			continue
+		}
+		for _, d := range linkedDrafts(cs, all) {
+			linked[d.ID] = true
+		}
+	}
+	var out []codacy.CodingStandard
+	for _, cs := range selected {
+		if !linked[cs.ID] {
+			out = append(out, cs)
+		}
+	}
+	return out
+}
+
+// ensureDraft returns an editable draft for the effective standard cs,
+// reusing the draft recorded in the journal or, depending on strategy, a draft
+// already present in all. created reports whether the draft was created by
+// this tool, rather than found, and so may be deleted again. In dry-run mode
+// nothing is created or deleted and cs itself is returned when no draft
+// exists yet.
+func ensureDraft(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName string,
+	cs codacy.CodingStandard,
+	all []codacy.CodingStandard,
+	strategy string,
+	dryRun bool,
+	jr *journal,
+) (draft codacy.CodingStandard, created bool, err error) {
+	if draftID, reused, ok := jr.draftFor(cs.ID); ok {
+		logger.Info("Reusing draft of the interrupted run", keyDraftID, draftID)
+		draft, err := client.GetCodingStandard(provider, orgName, draftID)
+		if err != nil {
+			return cs, false, fmt.Errorf("fetching journaled draft: %w", err)
+		}
+		return *draft, !reused, nil
+	}
+
+	existing := linkedDrafts(cs, all)
+	switch {
+	case strategy == draftReuse && len(existing) > 0:
+		logger.Info("Reusing existing draft", keyDraftID, existing[0].ID, "name", existing[0].Name)
+		if len(existing) > 1 {
+			logger.Info("Older drafts of this standard are left untouched", "count", len(existing)-1)
+		}
+		if !dryRun {
+			if err := jr.recordDraft(cs.ID, existing[0].ID, true); err != nil {
+				return existing[0], false, err
+			}
+		}
+		return existing[0], false, nil
+	case strategy == draftReplace:
+		for _, d := range existing {
+			if dryRun {
+				logger.Info("[dry-run] Would delete existing draft", keyDraftID, d.ID)
+				continue
+			}
+			if err := client.DeleteCodingStandard(provider, orgName, d.ID); err != nil {
+				return cs, false, fmt.Errorf("deleting existing draft: %w", err)
+			}
+			logger.Info("Deleted existing draft", keyDraftID, d.ID)
+		}
+	}
+
+	if dryRun {
+		logger.Info("[dry-run] Would create a draft from the standard")
+		return cs, true, nil
+	}
+	dup, err := client.CreateDraftFromStandard(provider, orgName, cs)
+	if err != nil {
+		return cs, false, fmt.Errorf("creating draft from standard: %w", err)
+	}
+	logger.Info("Created draft from the standard", keyDraftID, dup.ID, "name", dup.Name)
+	if err := jr.recordDraft(cs.ID, dup.ID, false); err != nil {
+		return *dup, true, err
+	}
+	return *dup, true, nil
+}
+
+// sourceStandard returns the effective standard in all that the draft d was
+// created from, using the same name-and-languages rule as linkedDrafts.
+func sourceStandard(d codacy.CodingStandard, all []codacy.CodingStandard) (codacy.CodingStandard, bool) {
+	for _, cs := range all {
+		if !cs.IsDraft && cs.Name == d.Name && sameLanguages(cs.Languages, d.Languages) {
+			return cs, true
+		}
+	}
+	return codacy.CodingStandard{}, false
+}
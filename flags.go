@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// connFlags holds the connection flags shared by every command.
+type connFlags struct {
+	fs          *flag.FlagSet
+	apiToken    *string
+	provider    *string
+	orgName     *string
+	apiURL      *string
+	configPath  *string
+	profile     *string
+	logLevel    *string
+	logFormat   *string
+	traceHTTP   *bool
+	traceHAR    *string
+	record      *string
+	replay      *string
+	metricsFile *string
+	metricsPush *string
+	metricsJob  *string
+	auditLog    *string
+
+	// shared is what the clients of one command have in common, such as
+	// the tracer of --trace-http.
+	shared *connShared
+}
+
+// connShared is set up by the first client a command creates and used by
+// every later one, e.g. both organisations' clients of sync.
+type connShared struct {
+	tracer    *codacy.Tracer
+	transport http.RoundTripper
+	audit     *auditLog
+}
+
+// addConnFlags registers the connection flags on fs.
+func addConnFlags(fs *flag.FlagSet) connFlags {
+	return connFlags{
+		fs:          fs,
+		apiToken:    fs.String("api-token", "", "Codacy API token (or set CODACY_API_TOKEN)"),
+		provider:    fs.String("provider", "gh", "Git provider: gh (GitHub), gl (GitLab), bb (Bitbucket)"),
+		orgName:     fs.String("organization", "", "Organisation name on the Git provider (required)"),
+		apiURL:      fs.String("api-url", "", "Base URL of the Codacy API, for self-hosted installations (default: https://app.codacy.com/api/v3)"),
+		configPath:  fs.String("config", "", "Configuration file with named profiles (default: codacy-security-toggler/config.yaml in the user configuration directory)"),
+		profile:     fs.String("profile", "", "Profile of the configuration file to use (or set CODACY_PROFILE)"),
+		logLevel:    fs.String("log-level", "info", "Minimum level of the log lines written to stderr: debug, info, warn or error"),
+		logFormat:   fs.String("log-format", "text", "Format of the log lines: text or json"),
+		traceHTTP:   fs.Bool("trace-http", false, "Log every HTTP request and response, with the API token masked"),
+		traceHAR:    fs.String("trace-har", "", "Write every HTTP request and response to this file in HAR format"),
+		record:      fs.String("record", "", "Record every HTTP exchange to this cassette file for --replay"),
+		replay:      fs.String("replay", "", "Answer every API call from this cassette file instead of the network"),
+		metricsFile: fs.String("metrics-file", "", "Write the metrics of the run to this file in Prometheus text format"),
+		metricsPush: fs.String("metrics-push-url", "", "Push the metrics of the run to the Prometheus push-gateway at this URL"),
+		metricsJob:  fs.String("metrics-job", "codacy-security-toggler", "Job name the metrics are pushed under"),
+		auditLog:    fs.String("audit-log", "", "Append a JSON line to this file for every change made through the API"),
+		shared:      &connShared{},
+	}
+}
+
+// setup prepares the connection flags of a command once its flags are
+// parsed. It fills in the flags not given on the command line from the
+// environment and the configuration profile, checks that the required
+// connection flags are set and the logging and cassette flags are valid, and
+// then sets up in order: the default logger, the cassette of --replay, the
+// audit log and the writing of the metrics. Every command calls it before
+// anything else.
+func (c connFlags) setup() error {
+	if err := applyConfig(c.fs, *c.configPath, *c.profile); err != nil {
+		return err
+	}
+	if *c.record != "" && *c.replay != "" {
+		return errors.New("give at most one of --record and --replay")
+	}
+	if c.token() == "" {
+		return errors.New("API token is required — use --api-token or set CODACY_API_TOKEN")
+	}
+	if *c.orgName == "" {
+		return errors.New("--organization is required")
+	}
+	logger, err := c.logger()
+	if err != nil {
+		return err
+	}
+
+	// The error a command fails with is logged by main in the same format.
+	slog.SetDefault(logger)
+	if *c.replay != "" {
+		r, err := codacy.LoadReplayer(*c.replay)
+		if err != nil {
+			return err
+		}
+		c.shared.transport = r
+		atFinish(func() error {
+			if n := r.Unused(); n > 0 {
+				logger.Warn("Not every recorded exchange was replayed — the run took a different course", "unused", n)
+			}
+			return nil
+		})
+	}
+	// A replayed run changes nothing, so there is nothing to audit.
+	if *c.auditLog != "" && *c.replay == "" {
+		a, err := openAuditLog(*c.auditLog)
+		if err != nil {
+			return err
+		}
+		c.shared.audit = a
+		atFinish(a.close)
+	}
+	if path := *c.metricsFile; path != "" {
+		atFinish(func() error { return saveMetrics(path) })
+	}
+	if gateway := *c.metricsPush; gateway != "" {
+		atFinish(func() error { return pushMetrics(gateway, *c.metricsJob, *c.provider, *c.orgName) })
+	}
+	return nil
+}
+
+// token returns the API token given by --api-token or CODACY_API_TOKEN, or
+// "" if there is none. A replayed run needs no token.
+func (c connFlags) token() string {
+	if *c.apiToken != "" {
+		return *c.apiToken
+	}
+	if token := os.Getenv("CODACY_API_TOKEN"); token != "" {
+		return token
+	}
+	if *c.replay != "" {
+		return "replay"
+	}
+	return ""
+}
+
+// client returns a Codacy API client for token honouring --api-url,
+// --record, --replay, the metrics flags, --trace-http, --trace-har and
+// --audit-log. Its requests belong to the span of the running command.
+func (c connFlags) client(token string) *codacy.Client {
+	var client *codacy.Client
+	var opts []codacy.Option
+	if *c.apiURL != "" {
+		opts = append(opts, codacy.WithBaseURL(*c.apiURL))
+	}
+	if rt := c.transport(); rt != nil {
+		opts = append(opts, codacy.WithTransport(rt))
+	}
+	if *c.metricsFile != "" || *c.metricsPush != "" {
+		opts = append(opts, codacy.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+			return metricsTransport{next: next}
+		}))
+	}
+	if t := c.tracer(); t != nil {
+		opts = append(opts, codacy.WithTracer(t))
+	}
+	opts = append(opts, codacy.WrapTransport(tracingTransport))
+	if a := c.shared.audit; a != nil {
+		// Clients may have different tokens, as in sync, so each looks up
+		// its own operator, once it first changes something.
+		operator := sync.OnceValue(func() auditOperator { return operatorOf(client) })
+		opts = append(opts, codacy.WithMutationHook(func(m codacy.Mutation) {
+			a.record(operator(), m)
+		}))
+	}
+	client = codacy.NewClient(token, opts...).WithContext(runContext)
+	return client
+}
+
+// transport returns the transport of --record or --replay, or nil to use the
+// network directly. The cassette of --record is written once the command
+// finishes.
+func (c connFlags) transport() http.RoundTripper {
+	if c.shared.transport != nil || *c.record == "" {
+		return c.shared.transport
+	}
+	r := codacy.NewRecorder(nil)
+	path := *c.record
+	atFinish(func() error { return r.Save(path) })
+	c.shared.transport = r
+	return r
+}
+
+// tracer returns the tracer of --trace-http and --trace-har, or nil when
+// neither is given. The HAR file is written once the command finishes.
+func (c connFlags) tracer() *codacy.Tracer {
+	if !*c.traceHTTP && *c.traceHAR == "" {
+		return nil
+	}
+	if c.shared.tracer != nil {
+		return c.shared.tracer
+	}
+	var logger *slog.Logger
+	if *c.traceHTTP {
+		logger, _ = c.logger() // validated by setup
+	}
+	t := codacy.NewTracer(logger, *c.traceHAR != "")
+	if path := *c.traceHAR; path != "" {
+		atFinish(func() error {
+			f, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("writing HAR file: %w", err)
+			}
+			if err := t.WriteHAR(f); err != nil {
+				f.Close()
+				return fmt.Errorf("writing HAR file: %w", err)
+			}
+			return f.Close()
+		})
+	}
+	c.shared.tracer = t
+	return t
+}
+
+// logger returns the logger configured by --log-level and --log-format. It
+// writes to stderr and tags every line with the organisation.
+func (c connFlags) logger() (*slog.Logger, error) {
+	logger, err := newLogger(os.Stderr, *c.logLevel, *c.logFormat)
+	if err != nil {
+		return nil, err
+	}
+	return logger.With("provider", *c.provider, keyOrg, *c.orgName), nil
+}
+
+// parseArgs parses the flags of a command from args. Commands take no
+// positional arguments, and flag parsing stops at the first one, so any
+// left over is an error rather than silently ignored along with the flags
+// after it.
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q — %s takes only flags", fs.Arg(0), fs.Name())
+	}
+	return nil
+}
+
+// matchesAny reports whether name matches one of the shell patterns (as in
+// path.Match). An empty list of patterns matches every name.
+func matchesAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// needsConfirmation reports whether a command must ask before making its
+// changes: not with --yes, not in a dry run, which changes nothing, and not
+// when stdin is not a terminal, as in CI jobs, where nobody can answer.
+func needsConfirmation(yes, dryRun bool) bool {
+	return !yes && !dryRun && stdinIsTerminal()
+}
+
+// confirm prints prompt and reports whether the user typed "yes" on stdin.
+func confirm(prompt string) bool {
+	fmt.Printf("%s Type 'yes' to continue: ", prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Println()
+		return false
+	}
+	return strings.TrimSpace(line) == "yes"
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal. When it is
+// not, nobody can answer a confirmation prompt.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// The null device is a character device too, and is what schedulers
+	// commonly attach to stdin.
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(item string) bool { return strings.EqualFold(item, s) })
+}
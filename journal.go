@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Journal step names.
+const (
+	stepRun          = "run"
+	stepDraftCreated = "draft-created"
+	stepDraftReused  = "draft-reused"
+	stepDraftDeleted = "draft-deleted"
+	stepToolUpdated  = "tool-updated"
+	stepPromoted     = "promoted"
+	stepRepoTool     = "repo-tool-updated"
+)
+
+// journalEntry is one JSON line of a run journal.
+type journalEntry struct {
+	Time         time.Time `json:"time"`
+	Step         string    `json:"step"`
+	Provider     string    `json:"provider,omitempty"`
+	Organization string    `json:"organization,omitempty"`
+	Enable       *bool     `json:"enable,omitempty"`
+	Categories   []string  `json:"categories,omitempty"`
+	StandardID   int64     `json:"standardId,omitempty"`
+	DraftID      int64     `json:"draftId,omitempty"`
+	Repository   string    `json:"repository,omitempty"`
+	ToolUUID     string    `json:"toolUuid,omitempty"`
+}
+
+// journal records the completed steps of a run so that an interrupted run can
+// be resumed without redoing work or creating additional drafts.
+// A nil *journal is valid and records nothing. A journal is safe for
+// concurrent use.
+type journal struct {
+	path     string
+	readOnly bool
+	// run is the first entry of a new journal. The file is only created
+	// with the first completed step, so that a run which changes nothing
+	// leaves no journal behind.
+	run *journalEntry
+	f   *os.File
+	enc *json.Encoder
+
+	mu sync.Mutex // guards the maps below and writes to f
+
+	drafts    map[int64]int64 // source standard ID -> draft ID
+	reused    map[int64]bool  // draft ID of drafts found rather than created
+	tools     map[string]bool // "draftID/toolUUID"
+	promoted  map[int64]bool  // draft ID
+	repoTools map[string]bool // "repo/toolUUID"
+}
+
+// openJournal opens the journal at path. When resume is true the existing
+// entries are loaded and must belong to a run with the same provider,
+// organisation, action and categories; otherwise a new journal is created once the first
+// step is recorded and path must not exist yet. When readOnly is true nothing
+// is written to disk.
+func openJournal(path string, resume, readOnly bool, provider, orgName string, enable bool, categories []string) (*journal, error) {
+	j := &journal{
+		path:      path,
+		readOnly:  readOnly,
+		drafts:    make(map[int64]int64),
+		reused:    make(map[int64]bool),
+		tools:     make(map[string]bool),
+		promoted:  make(map[int64]bool),
+		repoTools: make(map[string]bool),
+	}
+
+	if resume {
+		if err := j.load(provider, orgName, enable, categories); err != nil {
+			return nil, err
+		}
+		if readOnly {
+			return j, nil
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening journal: %w", err)
+		}
+		j.f = f
+		j.enc = json.NewEncoder(f)
+		return j, nil
+	}
+
+	if readOnly {
+		return j, nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, errJournalExists(path)
+	}
+	j.run = &journalEntry{Step: stepRun, Provider: provider, Organization: orgName, Enable: &enable, Categories: categories}
+	return j, nil
+}
+
+// create creates the file of a new journal and writes its run entry.
+func (j *journal) create() error {
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return errJournalExists(j.path)
+		}
+		return fmt.Errorf("creating journal: %w", err)
+	}
+	j.f = f
+	j.enc = json.NewEncoder(f)
+	run := *j.run
+	j.run = nil
+	return j.write(run)
+}
+
+func errJournalExists(path string) error {
+	return fmt.Errorf("journal %s already exists — use --resume to continue it", path)
+}
+
+// load reads the entries of an existing journal into memory.
+func (j *journal) load(provider, orgName string, enable bool, categories []string) error {
+	return readJournal(j.path, func(e journalEntry) error {
+		switch e.Step {
+		case stepRun:
+			if e.Provider != provider || e.Organization != orgName {
+				return fmt.Errorf("journal %s belongs to %s/%s, not %s/%s",
+					j.path, e.Provider, e.Organization, provider, orgName)
+			}
+			if e.Enable != nil && *e.Enable != enable {
+				return fmt.Errorf("journal %s was recorded with --enable=%v", j.path, *e.Enable)
+			}
+			// Journals older than --categories only toggled Security.
+			recorded := e.Categories
+			if len(recorded) == 0 {
+				recorded = []string{"Security"}
+			}
+			if !sameCategories(recorded, categories) {
+				return fmt.Errorf("journal %s was recorded with --categories=%s", j.path, strings.Join(recorded, ","))
+			}
+		case stepDraftCreated:
+			j.drafts[e.StandardID] = e.DraftID
+		case stepDraftReused:
+			j.drafts[e.StandardID] = e.DraftID
+			j.reused[e.DraftID] = true
+		case stepDraftDeleted:
+			if j.drafts[e.StandardID] == e.DraftID {
+				delete(j.drafts, e.StandardID)
+			}
+		case stepToolUpdated:
+			j.tools[toolKey(e.DraftID, e.ToolUUID)] = true
+		case stepPromoted:
+			j.promoted[e.DraftID] = true
+		case stepRepoTool:
+			j.repoTools[e.Repository+"/"+e.ToolUUID] = true
+		}
+		return nil
+	})
+}
+
+// readJournal calls fn for every entry of the journal at path, stopping at
+// the first error fn returns.
+func readJournal(path string, fn func(journalEntry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	var badLine error
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		// A run killed mid-write can leave a truncated last line, which is
+		// ignored; a malformed line anywhere else is an error.
+		if badLine != nil {
+			return badLine
+		}
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			badLine = fmt.Errorf("journal %s line %d: %w", path, line, err)
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading journal: %w", err)
+	}
+	return nil
+}
+
+func (j *journal) write(e journalEntry) error {
+	if j == nil || j.readOnly {
+		return nil
+	}
+	if j.run != nil {
+		if err := j.create(); err != nil {
+			return err
+		}
+	}
+	e.Time = time.Now().UTC()
+	if err := j.enc.Encode(e); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return j.f.Sync()
+}
+
+// Close closes the underlying journal file.
+func (j *journal) Close() error {
+	if j == nil || j.f == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// sameCategories reports whether a and b name the same categories, ignoring
+// case and order.
+func sameCategories(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, c := range a {
+		if !containsFold(b, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func toolKey(draftID int64, toolUUID string) string {
+	return fmt.Sprintf("%d/%s", draftID, toolUUID)
+}
+
+// draftFor returns the draft previously used for standardID, if any, and
+// whether it was an existing draft rather than one the tool created.
+func (j *journal) draftFor(standardID int64) (draftID int64, reused, ok bool) {
+	if j == nil {
+		return 0, false, false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	id, ok := j.drafts[standardID]
+	return id, j.reused[id], ok
+}
+
+// recordDraft records the draft used for standardID; reused is true for an
+// existing draft rather than one the tool created.
+func (j *journal) recordDraft(standardID, draftID int64, reused bool) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.drafts[standardID] = draftID
+	step := stepDraftCreated
+	if reused {
+		j.reused[draftID] = true
+		step = stepDraftReused
+	}
+	return j.write(journalEntry{Step: step, StandardID: standardID, DraftID: draftID})
+}
+
+// recordDraftDeleted records that the draft used for standardID was deleted,
+// so that a resumed run creates a new one.
+func (j *journal) recordDraftDeleted(standardID, draftID int64) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.drafts[standardID] == draftID {
+		delete(j.drafts, standardID)
+	}
+	return j.write(journalEntry{Step: stepDraftDeleted, StandardID: standardID, DraftID: draftID})
+}
+
+func (j *journal) toolDone(draftID int64, toolUUID string) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.tools[toolKey(draftID, toolUUID)]
+}
+
+func (j *journal) recordTool(draftID int64, toolUUID string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.tools[toolKey(draftID, toolUUID)] = true
+	return j.write(journalEntry{Step: stepToolUpdated, DraftID: draftID, ToolUUID: toolUUID})
+}
+
+func (j *journal) isPromoted(draftID int64) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.promoted[draftID]
+}
+
+func (j *journal) recordPromotion(draftID int64) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.promoted[draftID] = true
+	return j.write(journalEntry{Step: stepPromoted, DraftID: draftID})
+}
+
+func (j *journal) repoToolDone(repoName, toolUUID string) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.repoTools[repoName+"/"+toolUUID]
+}
+
+func (j *journal) recordRepoTool(repoName, toolUUID string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.repoTools[repoName+"/"+toolUUID] = true
+	return j.write(journalEntry{Step: stepRepoTool, Repository: repoName, ToolUUID: toolUUID})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// toolLanguages maps tool UUIDs to the languages each tool supports.
+type toolLanguages map[string][]string
+
+// loadToolLanguages fetches the language support of every tool in the Codacy
+// tools catalogue.
+func loadToolLanguages(client *codacy.Client) (toolLanguages, error) {
+	tools, err := client.ListTools()
+	if err != nil {
+		return nil, err
+	}
+	tl := make(toolLanguages, len(tools))
+	for _, t := range tools {
+		tl[t.UUID] = t.Languages
+	}
+	return tl, nil
+}
+
+// relevantTools splits tools into those supporting at least one of languages
+// and those that do not. When languages is empty, or a tool's language
+// support is unknown, the tool is considered relevant.
+func (tl toolLanguages) relevantTools(tools []codacy.AnalysisTool, languages []string) (relevant, skipped []codacy.AnalysisTool) {
+	if len(languages) == 0 {
+		return tools, nil
+	}
+	for _, t := range tools {
+		supported := tl[t.UUID]
+		if len(supported) == 0 || sharesLanguage(supported, languages) {
+			relevant = append(relevant, t)
+		} else {
+			skipped = append(skipped, t)
+		}
+	}
+	return relevant, skipped
+}
+
+// sharesLanguage reports whether a and b have a language in common, ignoring case.
+func sharesLanguage(a, b []string) bool {
+	return slices.ContainsFunc(a, func(l string) bool { return containsFold(b, l) })
+}
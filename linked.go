@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// runLinkedRepos implements the linked-repos command, which lists the
+// repositories linked to a coding standard.
+func runLinkedRepos(args []string) error {
+	fs := flag.NewFlagSet("linked-repos", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	csID := fs.Int64("coding-standard-id", 0, "ID of the coding standard (required)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler linked-repos [flags]
+
+Lists the repositories linked to a coding standard.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *csID == 0 {
+		fs.Usage()
+		return fmt.Errorf("--coding-standard-id is required")
+	}
+
+	client := conn.client(token)
+	repos, err := client.ListCodingStandardRepositories(*conn.provider, *conn.orgName, *csID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d repository(ies) linked to coding standard %d:\n", len(repos), *csID)
+	for _, r := range repos {
+		fmt.Printf("  - %s\n", r.Name)
+	}
+	return nil
+}
+
+// runMoveRepos implements the move-repos command, which links repositories to
+// another coding standard after previewing the Security patterns they gain or
+// lose.
+func runMoveRepos(args []string) error {
+	fs := flag.NewFlagSet("move-repos", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		fromID   = fs.Int64("from", 0, "ID of the standard to unlink the repositories from (0 = each repository's current standards)")
+		toID     = fs.Int64("to", 0, "ID of the standard to link the repositories to (required)")
+		repoList = fs.String("repositories", "", "Comma-separated repositories to move (required)")
+		yes      = fs.Bool("yes", false, "Move without asking for confirmation")
+		dryRun   = fs.Bool("dry-run", false, "Only show the preview")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler move-repos [flags]
+
+Moves repositories to another coding standard. Before anything changes, it
+shows which Security patterns each repository gains or loses.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	names := splitList(*repoList)
+	if *toID == 0 || len(names) == 0 {
+		fs.Usage()
+		return fmt.Errorf("--to and --repositories are required")
+	}
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+	client := conn.client(token)
+
+	// The standards each repository leaves.
+	sources := make(map[string][]int64, len(names))
+	if *fromID != 0 {
+		linked, err := client.ListCodingStandardRepositories(provider, orgName, *fromID)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if !slices.ContainsFunc(linked, func(r codacy.Repository) bool { return r.Name == name }) {
+				return fmt.Errorf("repository %s is not linked to coding standard %d", name, *fromID)
+			}
+			sources[name] = []int64{*fromID}
+		}
+	} else {
+		repos, err := client.ListRepositoriesWithAnalysis(provider, orgName)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			i := slices.IndexFunc(repos, func(r codacy.RepositoryWithAnalysis) bool { return r.Repository.Name == name })
+			if i < 0 {
+				return fmt.Errorf("repository %s not found", name)
+			}
+			for _, cs := range repos[i].Repository.Standards {
+				if cs.ID != *toID {
+					sources[name] = append(sources[name], cs.ID)
+				}
+			}
+		}
+	}
+
+	sets := make(map[int64]map[string]bool)
+	securitySet := func(id int64) (map[string]bool, error) {
+		if set, ok := sets[id]; ok {
+			return set, nil
+		}
+		set, err := activeSecurityPatterns(client, provider, orgName, id)
+		if err != nil {
+			return nil, err
+		}
+		sets[id] = set
+		return set, nil
+	}
+
+	target, err := securitySet(*toID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Moving %d repository(ies) to coding standard %d:\n", len(names), *toID)
+	for _, name := range names {
+		current := make(map[string]bool)
+		for _, id := range sources[name] {
+			set, err := securitySet(id)
+			if err != nil {
+				return err
+			}
+			for k := range set {
+				current[k] = true
+			}
+		}
+		gained, lost := setDifference(target, current), setDifference(current, target)
+		fmt.Printf("  %s (from %s): +%d / -%d Security pattern(s)\n", name, standardIDs(sources[name]), len(gained), len(lost))
+		for _, p := range gained {
+			fmt.Printf("      + %s\n", p)
+		}
+		for _, p := range lost {
+			fmt.Printf("      - %s\n", p)
+		}
+	}
+	fmt.Println()
+
+	if *dryRun {
+		fmt.Println("[dry-run] no repositories moved")
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Move %d repository(ies)?", len(names))) {
+		fmt.Println("Aborted — no repositories moved.")
+		return nil
+	}
+
+	result, err := client.AddRepositoriesToCodingStandard(provider, orgName, *toID, names)
+	if err != nil {
+		return err
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("could not link %d repo(s) to standard %d: %s — nothing unlinked",
+			len(result.Failed), *toID, strings.Join(result.Failed, ", "))
+	}
+	fmt.Printf("Linked %d repo(s) to standard %d\n", len(result.Successful), *toID)
+
+	unlink := make(map[int64][]string)
+	for _, name := range names {
+		for _, id := range sources[name] {
+			unlink[id] = append(unlink[id], name)
+		}
+	}
+	var failed int
+	for id, repos := range unlink {
+		result, err := client.RemoveRepositoriesFromCodingStandard(provider, orgName, id, repos)
+		if err != nil {
+			logger.Warn("Could not unlink repositories from standard", keyStandardID, id, "repositories", repos, "error", err)
+			failed += len(repos)
+			continue
+		}
+		if len(result.Failed) > 0 {
+			logger.Warn("Could not unlink repositories from standard", keyStandardID, id, "repositories", result.Failed)
+			failed += len(result.Failed)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d repository(ies) are still linked to their previous standard", failed)
+	}
+	return nil
+}
+
+// activeSecurityPatterns returns the Security-category patterns that are in
+// effect in a coding standard — enabled in an enabled tool — keyed as
+// "toolUUID/patternID".
+func activeSecurityPatterns(client *codacy.Client, provider, orgName string, csID int64) (map[string]bool, error) {
+	tools, err := client.ListCodingStandardTools(provider, orgName, csID)
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[string]bool)
+	for _, t := range tools {
+		if !t.IsEnabled {
+			continue
+		}
+		patterns, err := client.ListCodingStandardPatterns(provider, orgName, csID, t.UUID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range patterns {
+			if p.Enabled && containsFold([]string{"Security"}, p.PatternDefinition.Category) {
+				active[t.UUID+"/"+p.PatternDefinition.ID] = true
+			}
+		}
+	}
+	return active, nil
+}
+
+// setDifference returns the keys of a that are not in b, sorted.
+func setDifference(a, b map[string]bool) []string {
+	var diff []string
+	for k := range a {
+		if !b[k] {
+			diff = append(diff, k)
+		}
+	}
+	slices.Sort(diff)
+	return diff
+}
+
+func standardIDs(ids []int64) string {
+	if len(ids) == 0 {
+		return "no standard"
+	}
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("standard %d", id)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// validFormat checks the value of a --format flag.
+func validFormat(format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid --format %q — use text or json", format)
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// toolNames returns the names of the tools of the Codacy catalogue by UUID.
+// Names are informational, so a catalogue that cannot be listed yields an
+// empty map.
+func toolNames(client *codacy.Client) map[string]string {
+	names := make(map[string]string)
+	if catalogue, err := client.ListTools(); err == nil {
+		for _, t := range catalogue {
+			names[t.UUID] = t.Name
+		}
+	}
+	return names
+}
+
+// runListStandards implements the list-standards command.
+func runListStandards(args []string) error {
+	fs := flag.NewFlagSet("list-standards", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		drafts = fs.Bool("drafts", true, "Include draft coding standards")
+		format = fs.String("format", "text", "Output format: text or json")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler list-standards [flags]
+
+Lists the coding standards of the organisation with their languages and the
+number of enabled tools, enabled patterns and linked repositories.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if err := validFormat(*format); err != nil {
+		return err
+	}
+
+	client := conn.client(token)
+	all, err := client.ListCodingStandards(*conn.provider, *conn.orgName)
+	if err != nil {
+		return err
+	}
+	standards := make([]codacy.CodingStandard, 0, len(all))
+	for _, cs := range all {
+		if *drafts || !cs.IsDraft {
+			standards = append(standards, cs)
+		}
+	}
+
+	if *format == "json" {
+		return printJSON(standards)
+	}
+	fmt.Printf("%d coding standard(s):\n", len(standards))
+	for _, cs := range standards {
+		fmt.Printf("  [%d] %s  (draft=%v  default=%v  tools=%d  patterns=%d  repositories=%d)\n",
+			cs.ID, cs.Name, cs.IsDraft, cs.IsDefault,
+			cs.Meta.EnabledToolsCount, cs.Meta.EnabledPatternsCount, cs.Meta.LinkedRepositoriesCount)
+		if len(cs.Languages) > 0 {
+			fmt.Printf("      languages: %s\n", strings.Join(cs.Languages, ", "))
+		}
+	}
+	return nil
+}
+
+// runListRepos implements the list-repos command.
+func runListRepos(args []string) error {
+	fs := flag.NewFlagSet("list-repos", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		detached = fs.Bool("detached", false, "Only list repositories that follow no coding standard")
+		format   = fs.String("format", "text", "Output format: text or json")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler list-repos [flags]
+
+Lists the repositories of the organisation with their languages and the
+coding standards they follow.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if err := validFormat(*format); err != nil {
+		return err
+	}
+
+	client := conn.client(token)
+	repos, err := client.ListRepositoriesWithAnalysis(*conn.provider, *conn.orgName)
+	if err != nil {
+		return err
+	}
+	if *detached {
+		repos = detachedRepositories(repos)
+	}
+
+	if *format == "json" {
+		list := make([]codacy.Repository, 0, len(repos))
+		for _, r := range repos {
+			list = append(list, r.Repository)
+		}
+		return printJSON(list)
+	}
+	fmt.Printf("%d repository(ies):\n", len(repos))
+	for _, r := range repos {
+		standards := "none"
+		if len(r.Repository.Standards) > 0 {
+			names := make([]string, 0, len(r.Repository.Standards))
+			for _, cs := range r.Repository.Standards {
+				names = append(names, fmt.Sprintf("%s [%d]", cs.Name, cs.ID))
+			}
+			standards = strings.Join(names, ", ")
+		}
+		fmt.Printf("  - %s  (standards: %s)\n", r.Repository.Name, standards)
+		if len(r.Repository.Languages) > 0 {
+			fmt.Printf("      languages: %s\n", strings.Join(r.Repository.Languages, ", "))
+		}
+	}
+	return nil
+}
+
+// listedTool is one entry of the list-tools output.
+type listedTool struct {
+	UUID            string   `json:"uuid"`
+	Name            string   `json:"name,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+	FollowsStandard *bool    `json:"followsStandard,omitempty"`
+	Languages       []string `json:"languages,omitempty"`
+}
+
+// runListTools implements the list-tools command.
+func runListTools(args []string) error {
+	fs := flag.NewFlagSet("list-tools", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		csID     = fs.Int64("coding-standard-id", 0, "List the tools of this coding standard")
+		repoName = fs.String("repository", "", "List the tools of this repository")
+		format   = fs.String("format", "text", "Output format: text or json")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler list-tools [flags]
+
+Lists the tools of a coding standard or a repository and whether they are
+enabled. Without --coding-standard-id or --repository, lists the tools
+Codacy provides and the languages they support.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if err := validFormat(*format); err != nil {
+		return err
+	}
+	if *csID != 0 && *repoName != "" {
+		fs.Usage()
+		return fmt.Errorf("give at most one of --coding-standard-id and --repository")
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+	client := conn.client(token)
+
+	var tools []listedTool
+	switch {
+	case *csID != 0:
+		csTools, err := client.ListCodingStandardTools(provider, orgName, *csID)
+		if err != nil {
+			return err
+		}
+		names := toolNames(client)
+		for _, t := range csTools {
+			enabled := t.IsEnabled
+			tools = append(tools, listedTool{UUID: t.UUID, Name: names[t.UUID], Enabled: &enabled})
+		}
+	case *repoName != "":
+		repoTools, err := client.ListRepositoryTools(provider, orgName, *repoName)
+		if err != nil {
+			return err
+		}
+		for _, t := range repoTools {
+			enabled, follows := t.Settings.IsEnabled, t.Settings.FollowsStandard
+			tools = append(tools, listedTool{UUID: t.UUID, Name: t.Name, Enabled: &enabled, FollowsStandard: &follows})
+		}
+	default:
+		catalogue, err := client.ListTools()
+		if err != nil {
+			return err
+		}
+		for _, t := range catalogue {
+			tools = append(tools, listedTool{UUID: t.UUID, Name: t.Name, Languages: t.Languages})
+		}
+	}
+
+	if *format == "json" {
+		if tools == nil {
+			tools = []listedTool{}
+		}
+		return printJSON(tools)
+	}
+	fmt.Printf("%d tool(s):\n", len(tools))
+	for _, t := range tools {
+		var details []string
+		if t.Enabled != nil {
+			details = append(details, enabledWord(*t.Enabled))
+		}
+		if t.FollowsStandard != nil && !*t.FollowsStandard {
+			details = append(details, "overrides the standard")
+		}
+		if len(t.Languages) > 0 {
+			details = append(details, strings.Join(t.Languages, ", "))
+		}
+		name := t.Name
+		if name == "" {
+			name = "(unknown)"
+		}
+		fmt.Printf("  %s  %s", t.UUID, name)
+		if len(details) > 0 {
+			fmt.Printf("  (%s)", strings.Join(details, "; "))
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Attribute keys shared by every log line that concerns the entity.
+const (
+	keyOrg        = "org"
+	keyStandardID = "standard_id"
+	keyDraftID    = "draft_id"
+	keyRepo       = "repo"
+	keyToolUUID   = "tool_uuid"
+)
+
+// newLogger returns a logger writing to w at the given level (debug, info,
+// warn or error) in the given format (text or json).
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid --log-level %q — use debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid --log-format %q — use text or json", format)
+}
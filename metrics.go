@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// The metrics of a run. They are always collected and only written out when
+// --metrics-file or --metrics-push-url is given.
+var (
+	apiRequests = newCounter("codacy_toggler_api_requests_total",
+		"Codacy API calls by endpoint and status; status is \"error\" when no response was received.",
+		"method", "endpoint", "status")
+	apiLatency = newHistogram("codacy_toggler_api_request_duration_seconds",
+		"Latency of the Codacy API calls by endpoint.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		"method", "endpoint")
+	toolsUpdated = newCounter("codacy_toggler_tools_updated_total",
+		"Tools whose patterns were toggled, in coding standards or directly in repositories.",
+		"target")
+	toolsFailed = newCounter("codacy_toggler_tools_failed_total",
+		"Tools whose patterns could not be toggled, in coding standards or directly in repositories.",
+		"target")
+	standardsPromoted = newCounter("codacy_toggler_standards_promoted_total",
+		"Drafts promoted to effective coding standards.")
+	detachedPatched = newCounter("codacy_toggler_detached_repositories_patched_total",
+		"Repositories following no coding standard whose tools were all toggled directly.")
+)
+
+// Values of the target label of toolsUpdated and toolsFailed.
+const (
+	targetStandard   = "standard"
+	targetRepository = "repository"
+)
+
+// metricFamilies lists the metrics in the order they are written.
+var metricFamilies []metricFamily
+
+type metricFamily interface {
+	write(w io.Writer)
+}
+
+// counter is a Prometheus counter with labels.
+type counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64 // by encoded label values
+}
+
+func newCounter(name, help string, labels ...string) *counter {
+	c := &counter{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	metricFamilies = append(metricFamilies, c)
+	return c
+}
+
+// add adds v to the series with the given label values.
+func (c *counter) add(v float64, labelValues ...string) {
+	key := labelPairs(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *counter) inc(labelValues ...string) {
+	c.add(1, labelValues...)
+}
+
+func (c *counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	if len(c.labels) == 0 && len(c.values) == 0 {
+		fmt.Fprintf(w, "%s 0\n", c.name)
+	}
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, braced(key), formatValue(c.values[key]))
+	}
+}
+
+// histogram is a Prometheus histogram with labels.
+type histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries // by encoded label values
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name, help string, buckets []float64, labels ...string) *histogram {
+	h := &histogram{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+	metricFamilies = append(metricFamilies, h)
+	return h
+}
+
+// observe records v in the series with the given label values.
+func (h *histogram) observe(v float64, labelValues ...string) {
+	key := labelPairs(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braced(joinPairs(key, `le="`+formatValue(upper)+`"`)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braced(joinPairs(key, `le="+Inf"`)), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, braced(key), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, braced(key), s.count)
+	}
+}
+
+// labelPairs encodes label names and values as in the text format, e.g.
+// method="GET",status="200".
+func labelPairs(names, values []string) string {
+	if len(names) != len(values) {
+		panic(fmt.Sprintf("metric with labels %v given values %v", names, values))
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func joinPairs(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func braced(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+	return "{" + pairs + "}"
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeMetrics writes every metric to w in the Prometheus text format.
+func writeMetrics(w io.Writer) {
+	for _, m := range metricFamilies {
+		m.write(w)
+	}
+}
+
+// metricsTransport counts the requests sent through next and measures their
+// latency by endpoint.
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := codacy.Endpoint(req.URL.Path)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	apiLatency.observe(time.Since(start).Seconds(), req.Method, endpoint)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	apiRequests.inc(req.Method, endpoint, status)
+	return resp, err
+}
+
+// saveMetrics writes every metric to the file at path, replacing it at once
+// so that a collector reading the file never sees part of a run.
+func saveMetrics(path string) error {
+	var buf bytes.Buffer
+	writeMetrics(&buf)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	return nil
+}
+
+// pushMetrics replaces the metrics of the group of job and the organisation
+// on the Prometheus push-gateway at gatewayURL.
+func pushMetrics(gatewayURL, job, provider, orgName string) error {
+	var buf bytes.Buffer
+	writeMetrics(&buf)
+	endpoint := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job) +
+		"/provider/" + url.PathEscape(provider) + "/organization/" + url.PathEscape(orgName)
+	req, err := http.NewRequest(http.MethodPut, endpoint, &buf)
+	if err != nil {
+		return fmt.Errorf("pushing metrics: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 300))
+		return fmt.Errorf("pushing metrics: push-gateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
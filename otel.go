@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// tracer creates the spans of a run. Until setupTracing installs an exporter
+// they are not recorded.
+var tracer = otel.Tracer("github.com/codacy/codacy-security-toggler")
+
+// runContext carries the span of the running command. The clients created by
+// connFlags.client make their requests with it.
+var runContext = context.Background()
+
+// setupTracing installs an OTLP trace exporter when the standard OpenTelemetry
+// environment variables ask for one, i.e. when OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set or OTEL_TRACES_EXPORTER is otlp.
+// The exporter, the sampler and the resource are configured by those
+// variables too. Pending spans are flushed once the command finishes.
+func setupTracing() error {
+	if !otlpTracesRequested() {
+		return nil
+	}
+	ctx := context.Background()
+
+	var client otlptrace.Client
+	switch protocol := otlpTracesProtocol(); protocol {
+	case "http/protobuf":
+		client = otlptracehttp.NewClient()
+	case "grpc":
+		client = otlptracegrpc.NewClient()
+	default:
+		return fmt.Errorf("unsupported OTLP protocol %q — use http/protobuf or grpc", protocol)
+	}
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName("codacy-security-toggler")),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	)
+	if err != nil {
+		return fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	atFinish(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			return fmt.Errorf("flushing traces: %w", err)
+		}
+		return nil
+	})
+	return nil
+}
+
+func otlpTracesRequested() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	switch os.Getenv("OTEL_TRACES_EXPORTER") {
+	case "otlp":
+		return true
+	case "":
+		return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	}
+	return false // none, or an exporter this tool does not support
+}
+
+func otlpTracesProtocol() string {
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if p := os.Getenv(env); p != "" {
+			return p
+		}
+	}
+	return "http/protobuf"
+}
+
+// tracingTransport wraps next so that every API call gets a span named after
+// its endpoint, a child of the span the request's client carries.
+func tracingTransport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + codacy.Endpoint(r.URL.Path)
+	}))
+}
+
+// startSpan starts a span named name as a child of the span client carries,
+// and returns a client whose requests belong to the new span.
+func startSpan(client *codacy.Client, name string, attrs ...attribute.KeyValue) (*codacy.Client, trace.Span) {
+	ctx, span := tracer.Start(client.Context(), name, trace.WithAttributes(attrs...))
+	return client.WithContext(ctx), span
+}
+
+// endSpan ends span, marking it as failed with err when err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// Modes for tools that do not follow the coding standard of their repository.
+const (
+	overridesIgnore   = "ignore"   // do not look for overridden tools
+	overridesReport   = "report"   // list overridden tools without changing them
+	overridesPatch    = "patch"    // toggle the overridden tools' patterns directly
+	overridesReattach = "reattach" // re-apply the repository's standards to it
+)
+
+func validOverridesMode(s string) bool {
+	return s == overridesIgnore || s == overridesReport || s == overridesPatch || s == overridesReattach
+}
+
+// overrideReport is the outcome of handling the overridden tools of one repository.
+type overrideReport struct {
+	Repository  string   `json:"repository"`
+	Tools       []string `json:"tools"`
+	Action      string   `json:"action"`
+	FailedTools []string `json:"failedTools,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// processOverriddenTools looks for repositories that follow a coding standard
+// but have tools configured locally (FollowsStandard=false), which are not
+// affected by phase 1. Depending on opts.overriddenTools those tools are only
+// reported, patched directly, or the repository is re-attached to its
+// standards so that all of its tools follow them again.
+func processOverriddenTools(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName string,
+	repos []codacy.RepositoryWithAnalysis,
+	opts toggleOptions,
+	jr *journal,
+	report *runReport,
+) error {
+	var found, hadError bool
+	for _, r := range repos {
+		if len(r.Repository.Standards) == 0 {
+			continue
+		}
+		repoName := r.Repository.Name
+		repoLogger := logger.With(keyRepo, repoName)
+		client, span := startSpan(client, "repository", attribute.String(keyRepo, repoName))
+
+		tools, err := client.ListRepositoryTools(provider, orgName, repoName)
+		if err != nil {
+			repoLogger.Error("Could not list tools", "error", err)
+			report.Overridden = append(report.Overridden, overrideReport{
+				Repository: repoName, Action: opts.overriddenTools, Error: err.Error(),
+			})
+			hadError = true
+			endSpan(span, err)
+			continue
+		}
+		var overridden []codacy.AnalysisTool
+		for _, t := range tools {
+			if !t.Settings.FollowsStandard {
+				overridden = append(overridden, t)
+			}
+		}
+		if len(overridden) == 0 {
+			span.End()
+			continue
+		}
+		found = true
+
+		names := make([]string, len(overridden))
+		for i, t := range overridden {
+			names[i] = t.Name
+		}
+		repoLogger.Info("Found tools not following the standard", "count", len(overridden), "tools", names)
+		rep := overrideReport{Repository: repoName, Tools: names, Action: opts.overriddenTools}
+		span.SetAttributes(attribute.StringSlice("overridden_tools", names))
+
+		switch opts.overriddenTools {
+		case overridesPatch:
+			failed, err := patchTools(client, repoLogger, provider, orgName, repoName, overridden, opts, jr)
+			rep.FailedTools = failed
+			if err != nil {
+				repoLogger.Error("Could not patch overridden tools", "error", err)
+				rep.Error = err.Error()
+				hadError = true
+			}
+		case overridesReattach:
+			if err := reattachRepository(client, repoLogger, provider, orgName, r.Repository, opts.dryRun); err != nil {
+				repoLogger.Error("Could not re-attach repository", "error", err)
+				rep.Error = err.Error()
+				hadError = true
+			}
+		}
+		report.Overridden = append(report.Overridden, rep)
+		if rep.Error != "" {
+			endSpan(span, errors.New(rep.Error))
+		} else {
+			span.End()
+		}
+	}
+
+	if !found {
+		logger.Info("No overridden tools found")
+	}
+	if hadError {
+		return fmt.Errorf("one or more repositories with overridden tools could not be handled")
+	}
+	return nil
+}
+
+// reattachRepository re-applies every coding standard repo follows, which
+// makes all of its tools follow the standard again and drops local overrides.
+func reattachRepository(client *codacy.Client, logger *slog.Logger, provider, orgName string, repo codacy.Repository, dryRun bool) error {
+	for _, cs := range repo.Standards {
+		if dryRun {
+			logger.Info("[dry-run] Would re-attach to standard", keyStandardID, cs.ID, "name", cs.Name)
+			continue
+		}
+		result, err := client.AddRepositoriesToCodingStandard(provider, orgName, cs.ID, []string{repo.Name})
+		if err != nil {
+			return fmt.Errorf("re-attaching to standard %d: %w", cs.ID, err)
+		}
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("re-attaching to standard %d failed", cs.ID)
+		}
+		logger.Info("Re-attached to standard", keyStandardID, cs.ID, "name", cs.Name)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// runPromote implements the promote command, which promotes draft coding
+// standards prepared earlier, e.g. by toggle --promote=false, after showing
+// how each differs from the live standard it replaces.
+func runPromote(args []string) error {
+	fs := flag.NewFlagSet("promote", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		ids        = fs.String("coding-standard-id", "", "Comma-separated IDs of the drafts to promote")
+		names      = fs.String("name", "", "Comma-separated names of the drafts to promote; shell patterns are allowed")
+		fromReport = fs.String("from-report", "", "Promote the drafts a previous toggle run left unpromoted, read from its --report file")
+		categories = fs.String("category", "", "Comma-separated pattern categories to show in the diff (default: all)")
+		retries    = fs.Int("promote-retries", 2, "Times to retry applying a promoted standard to the repositories it failed for")
+		yes        = fs.Bool("yes", false, "Promote without asking for confirmation")
+		dryRun     = fs.Bool("dry-run", false, "Show the drafts and their diffs without promoting them")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler promote [flags]
+
+Promotes draft coding standards, replacing the effective standards they were
+created from and applying them to those standards' repositories. Drafts are
+selected by ID, by name, or from the report of a toggle run that left them
+unpromoted. The differences between each draft and its live standard are
+shown before anything changes.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *ids == "" && *names == "" && *fromReport == "" {
+		fs.Usage()
+		return errors.New("give --coding-standard-id, --name or --from-report")
+	}
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+	client := conn.client(token)
+
+	wanted := make(map[int64]bool)
+	for _, s := range splitList(*ids) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid coding standard ID %q", s)
+		}
+		wanted[id] = true
+	}
+	var reportIDs []int64
+	if *fromReport != "" {
+		if reportIDs, err = unpromotedDrafts(*fromReport, provider, orgName); err != nil {
+			return err
+		}
+		if len(reportIDs) == 0 {
+			fmt.Printf("%s lists no unpromoted drafts.\n", *fromReport)
+		}
+	}
+
+	all, err := client.ListCodingStandards(provider, orgName)
+	if err != nil {
+		return err
+	}
+	// A report may predate drafts being promoted or deleted some other way.
+	for _, id := range reportIDs {
+		if wanted[id] {
+			continue
+		}
+		if slices.ContainsFunc(all, func(cs codacy.CodingStandard) bool { return cs.ID == id && cs.IsDraft }) {
+			wanted[id] = true
+		} else {
+			fmt.Printf("Skipping %d from the report — it is no longer a draft\n", id)
+		}
+	}
+	drafts, err := selectDrafts(all, wanted, splitList(*names))
+	if err != nil {
+		return err
+	}
+	if len(drafts) == 0 {
+		fmt.Println("No drafts to promote.")
+		return nil
+	}
+
+	cats := splitList(*categories)
+	for _, draft := range drafts {
+		fmt.Printf("==> Draft %q (ID %d)\n", draft.Name, draft.ID)
+		src, ok := sourceStandard(draft, all)
+		if !ok {
+			fmt.Println("    No live standard with the same name and languages — it becomes a new standard")
+			fmt.Println()
+			continue
+		}
+		fmt.Printf("    Replaces %q (ID %d), linked to %d repository(ies)\n",
+			src.Name, src.ID, src.Meta.LinkedRepositoriesCount)
+		d, err := diffStandards(client, provider, orgName, src.ID, draft.ID)
+		if err != nil {
+			return err
+		}
+		printDiff(os.Stdout, d.filterCategories(cats), "    ")
+		fmt.Println()
+	}
+
+	if *dryRun {
+		fmt.Printf("[dry-run] would promote %d draft(s)\n", len(drafts))
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Promote %d draft(s)?", len(drafts))) {
+		fmt.Println("Aborted — nothing changed.")
+		return nil
+	}
+
+	opts := toggleOptions{promoteRetries: *retries}
+	var failed []string
+	for _, draft := range drafts {
+		fmt.Printf("==> Promoting %q (ID %d)\n", draft.Name, draft.ID)
+		client, span := startSpan(client, "promote draft",
+			attribute.Int64(keyDraftID, draft.ID), attribute.String("name", draft.Name))
+		result, err := client.PromoteDraftCodingStandard(provider, orgName, draft.ID)
+		if err != nil {
+			logger.Error("Could not promote draft", keyDraftID, draft.ID, "error", err)
+			failed = append(failed, fmt.Sprint(draft.ID))
+			endSpan(span, err)
+			continue
+		}
+		standardsPromoted.inc()
+		fmt.Println("    Promoted successfully!")
+		if len(result.Successful) > 0 {
+			fmt.Printf("    Applied to %d repo(s): %s\n", len(result.Successful), strings.Join(result.Successful, ", "))
+		}
+		if len(result.Failed) > 0 {
+			fmt.Printf("    Failed for %d repo(s): %s\n", len(result.Failed), strings.Join(result.Failed, ", "))
+			for _, f := range handlePromotionFailures(client, logger.With(keyStandardID, draft.ID), provider, orgName, draft.ID, result.Failed, opts, nil) {
+				if !f.Resolved {
+					fmt.Printf("    Still failing: %s — %s\n", f.Name, f.Reason)
+					failed = append(failed, fmt.Sprintf("%d (%s)", draft.ID, f.Name))
+				}
+			}
+		}
+		span.End()
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not promote or apply: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// selectDrafts returns the drafts of all whose ID is in ids or whose name
+// matches one of names. Every ID must be a draft.
+func selectDrafts(all []codacy.CodingStandard, ids map[int64]bool, names []string) ([]codacy.CodingStandard, error) {
+	var drafts []codacy.CodingStandard
+	for _, id := range sortedIDs(ids) {
+		i := slices.IndexFunc(all, func(cs codacy.CodingStandard) bool { return cs.ID == id })
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("coding standard %d not found", id)
+		case !all[i].IsDraft:
+			return nil, fmt.Errorf("coding standard %d is not a draft", id)
+		}
+		drafts = append(drafts, all[i])
+	}
+	if len(names) > 0 {
+		for _, cs := range all {
+			if cs.IsDraft && !ids[cs.ID] && matchesAny(names, cs.Name) {
+				drafts = append(drafts, cs)
+			}
+		}
+	}
+	return drafts, nil
+}
+
+func sortedIDs(set map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// unpromotedDrafts returns the IDs of the drafts a toggle run updated but did
+// not promote, according to its report, which must belong to the same
+// organisation. A standard processed without a separate draft is listed by
+// its own ID, which may not be a draft.
+func unpromotedDrafts(path, provider, orgName string) ([]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading report: %w", err)
+	}
+	var r runReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("decoding report %s: %w", path, err)
+	}
+	if r.Provider != provider || r.Organization != orgName {
+		return nil, fmt.Errorf("report %s belongs to %s/%s, not %s/%s", path, r.Provider, r.Organization, provider, orgName)
+	}
+	if r.DryRun {
+		return nil, fmt.Errorf("report %s is from a dry run, which creates no drafts", path)
+	}
+	var ids []int64
+	for _, s := range r.Standards {
+		if s.Promoted || s.Skipped != "" || s.DraftDeleted {
+			continue
+		}
+		id := s.DraftID
+		if id == 0 {
+			id = s.ID // the standard was itself a draft
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// promotionRetryDelay is the base delay between attempts to re-apply a
+// promoted standard to the repositories it failed for; attempt n waits n times
+// as long.
+const promotionRetryDelay = 2 * time.Second
+
+// repositoryFailure records a repository a promoted standard could not be
+// applied to and how that was resolved.
+type repositoryFailure struct {
+	Name     string `json:"name"`
+	Reason   string `json:"reason"`
+	Resolved bool   `json:"resolved"`
+}
+
+// handlePromotionFailures retries applying the promoted standard csID to the
+// repositories in failed and, when opts.fallbackPatch is set, patches the
+// toggled patterns of those still failing directly. It returns one entry per
+// repository in failed.
+func handlePromotionFailures(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName string,
+	csID int64,
+	failed []string,
+	opts toggleOptions,
+	jr *journal,
+) []repositoryFailure {
+	reasons := make(map[string]string, len(failed))
+	for _, name := range failed {
+		reasons[name] = "promotion could not apply the standard"
+	}
+
+	pending := failed
+	for attempt := 1; attempt <= opts.promoteRetries && len(pending) > 0; attempt++ {
+		time.Sleep(time.Duration(attempt) * promotionRetryDelay)
+		logger.Info("Retrying to apply the standard", "repositories", pending, "attempt", attempt, "max_attempts", opts.promoteRetries)
+		result, err := client.AddRepositoriesToCodingStandard(provider, orgName, csID, pending)
+		if err != nil {
+			logger.Warn("Retry failed", "attempt", attempt, "error", err)
+			for _, name := range pending {
+				reasons[name] = fmt.Sprintf("applying the standard failed after %d retry(ies): %v", attempt, err)
+			}
+			continue
+		}
+		for _, name := range result.Successful {
+			delete(reasons, name)
+		}
+		for _, name := range result.Failed {
+			reasons[name] = fmt.Sprintf("applying the standard failed after %d retry(ies)", attempt)
+		}
+		pending = result.Failed
+	}
+
+	var failures []repositoryFailure
+	for _, name := range failed {
+		reason, stillFailing := reasons[name]
+		if !stillFailing {
+			logger.Info("Applied the standard on retry", keyRepo, name)
+			failures = append(failures, repositoryFailure{Name: name, Reason: "applied on retry", Resolved: true})
+			continue
+		}
+		if opts.fallbackPatch {
+			repoLogger := logger.With(keyRepo, name)
+			repoLogger.Info("Patching repository directly")
+			total, failedTools, err := patchRepositoryTools(client, repoLogger, provider, orgName, name, opts, jr)
+			switch {
+			case err != nil:
+				reason += "; direct patch failed: " + err.Error()
+			case len(failedTools) > 0:
+				reason += fmt.Sprintf("; direct patch failed for %d of %d tool(s): %s",
+					len(failedTools), total, strings.Join(failedTools, ", "))
+			default:
+				failures = append(failures, repositoryFailure{Name: name, Reason: reason + "; patched directly", Resolved: true})
+				continue
+			}
+		}
+		failures = append(failures, repositoryFailure{Name: name, Reason: reason})
+	}
+	return failures
+}
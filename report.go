@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// runReport is the outcome of a toggle run. It is summarised at the end of the
+// run and written as JSON with --report.
+type runReport struct {
+	Provider     string             `json:"provider"`
+	Organization string             `json:"organization"`
+	Enable       bool               `json:"enable"`
+	DryRun       bool               `json:"dryRun"`
+	Phases       []string           `json:"phases"`
+	StartedAt    time.Time          `json:"startedAt"`
+	FinishedAt   time.Time          `json:"finishedAt"`
+	Standards    []standardReport   `json:"standards"`
+	Detached     []repositoryReport `json:"detachedRepositories"`
+	Overridden   []overrideReport   `json:"overriddenTools,omitempty"`
+
+	// DefaultStandardID is the standard new repositories inherit, 0 if none.
+	DefaultStandardID int64 `json:"defaultStandardId,omitempty"`
+}
+
+// standardReport is the outcome of processing one coding standard.
+type standardReport struct {
+	ID                int64    `json:"id"`
+	Name              string   `json:"name"`
+	DraftID           int64    `json:"draftId,omitempty"`
+	Skipped           string   `json:"skipped,omitempty"`
+	ToolsUpdated      int      `json:"toolsUpdated"`
+	FailedTools       []string `json:"failedTools,omitempty"`
+	Promoted          bool     `json:"promoted"`
+	PromotionWithheld string   `json:"promotionWithheld,omitempty"`
+	DraftDeleted      bool     `json:"draftDeleted,omitempty"`
+	Error             string   `json:"error,omitempty"`
+
+	AppliedRepositories int                 `json:"appliedRepositories,omitempty"`
+	FailedRepositories  []repositoryFailure `json:"failedRepositories,omitempty"`
+}
+
+// repositoryReport is the outcome of processing one detached repository.
+type repositoryReport struct {
+	Name         string   `json:"name"`
+	ToolsUpdated int      `json:"toolsUpdated"`
+	FailedTools  []string `json:"failedTools,omitempty"`
+	SkippedTools []string `json:"skippedTools,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// printSummary prints the per-standard outcome of the run, including why any
+// promotion was withheld.
+func (r *runReport) printSummary() {
+	var promoted, withheld, skipped, failed int
+	for _, s := range r.Standards {
+		switch {
+		case s.Error != "":
+			failed++
+		case s.Skipped != "":
+			skipped++
+		case s.Promoted:
+			promoted++
+		case s.PromotionWithheld != "":
+			withheld++
+		}
+	}
+	fmt.Println("--- Summary ---")
+	if slices.Contains(r.Phases, phaseStandards) {
+		fmt.Printf("  Standards: %d promoted, %d not promoted, %d skipped, %d failed\n",
+			promoted, withheld, skipped, failed)
+	}
+	for _, s := range r.Standards {
+		if s.PromotionWithheld != "" {
+			fmt.Printf("  [%d] %s — not promoted: %s\n", s.ID, s.Name, s.PromotionWithheld)
+		}
+		for _, f := range s.FailedRepositories {
+			if !f.Resolved {
+				fmt.Printf("  [%d] %s — not applied to %s: %s\n", s.ID, s.Name, f.Name, f.Reason)
+			}
+		}
+	}
+	var repoFailed int
+	for _, repo := range r.Detached {
+		if repo.Error != "" || len(repo.FailedTools) > 0 {
+			repoFailed++
+		}
+	}
+	if slices.Contains(r.Phases, phaseDetached) {
+		fmt.Printf("  Detached repositories: %d processed, %d with failures\n", len(r.Detached), repoFailed)
+	}
+	if len(r.Overridden) > 0 {
+		fmt.Printf("  Repositories with tools overriding their standard: %d\n", len(r.Overridden))
+	}
+	switch {
+	case r.DefaultStandardID == 0:
+		fmt.Println("  Default standard: none — new repositories follow no coding standard (see set-default)")
+	case r.promotedStandard(r.DefaultStandardID):
+		fmt.Printf("  Default standard: ID %d — promoted by this run, so new repositories inherit its changes\n", r.DefaultStandardID)
+	default:
+		fmt.Printf("  Default standard: ID %d\n", r.DefaultStandardID)
+	}
+	fmt.Println()
+}
+
+// promotedStandard reports whether the run promoted the standard with the
+// given ID. A promoted draft becomes the effective standard, keeping its ID.
+func (r *runReport) promotedStandard(id int64) bool {
+	for _, s := range r.Standards {
+		if !s.Promoted {
+			continue
+		}
+		if s.DraftID == id || s.DraftID == 0 && s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// write saves the report as indented JSON to path.
+func (r *runReport) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// runSetDefault implements the set-default command, which marks a coding
+// standard as the default applied to new repositories.
+func runSetDefault(args []string) error {
+	fs := flag.NewFlagSet("set-default", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		csID   = fs.Int64("coding-standard-id", 0, "ID of the coding standard to make the default (required)")
+		dryRun = fs.Bool("dry-run", false, "Show the current and new default without changing anything")
+		yes    = fs.Bool("yes", false, "Change the default without asking for confirmation")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler set-default [flags]
+
+Makes a coding standard the default for new repositories, so that they
+inherit its Security patterns automatically.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *csID == 0 {
+		fs.Usage()
+		return fmt.Errorf("--coding-standard-id is required")
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+
+	client := conn.client(token)
+	all, err := client.ListCodingStandards(provider, orgName)
+	if err != nil {
+		return err
+	}
+	var target *codacy.CodingStandard
+	for i, cs := range all {
+		if cs.ID == *csID {
+			target = &all[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("coding standard %d not found", *csID)
+	}
+	if target.IsDraft {
+		return fmt.Errorf("coding standard %d is a draft — promote it first", target.ID)
+	}
+
+	if current, ok := defaultStandard(all); ok {
+		fmt.Printf("Current default: %q (ID %d)\n", current.Name, current.ID)
+		if current.ID == target.ID {
+			fmt.Println("Nothing to do.")
+			return nil
+		}
+	} else {
+		fmt.Println("Current default: none")
+	}
+
+	if *dryRun {
+		fmt.Printf("[dry-run] would make %q (ID %d) the default\n", target.Name, target.ID)
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Make %q (ID %d) the default for new repositories?", target.Name, target.ID)) {
+		fmt.Println("Aborted — default unchanged.")
+		return nil
+	}
+	if err := client.SetDefaultCodingStandard(provider, orgName, target.ID); err != nil {
+		return err
+	}
+	fmt.Printf("New default:     %q (ID %d)\n", target.Name, target.ID)
+	return nil
+}
+
+// defaultStandard returns the effective coding standard marked as default, if any.
+func defaultStandard(all []codacy.CodingStandard) (codacy.CodingStandard, bool) {
+	for _, cs := range all {
+		if cs.IsDefault && !cs.IsDraft {
+			return cs, true
+		}
+	}
+	return codacy.CodingStandard{}, false
+}
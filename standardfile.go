@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// standardFile is the file representation of a coding standard used by the
+// export and import commands.
+type standardFile struct {
+	Name      string     `json:"name" yaml:"name"`
+	Languages []string   `json:"languages" yaml:"languages"`
+	Tools     []toolFile `json:"tools" yaml:"tools"`
+}
+
+// toolFile is the configuration of one tool in a standardFile.
+type toolFile struct {
+	UUID     string        `json:"uuid" yaml:"uuid"`
+	Name     string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Enabled  bool          `json:"enabled" yaml:"enabled"`
+	Patterns []patternFile `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+}
+
+// patternFile is the configuration of one pattern in a toolFile. Category is
+// informational and ignored on import.
+type patternFile struct {
+	ID         string            `json:"id" yaml:"id"`
+	Category   string            `json:"category,omitempty" yaml:"category,omitempty"`
+	Enabled    bool              `json:"enabled" yaml:"enabled"`
+	Parameters map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// isYAMLPath reports whether path has a YAML file extension.
+func isYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// readStandardFile decodes a standardFile from a JSON or YAML file, chosen by
+// its extension.
+func readStandardFile(path string) (*standardFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sf standardFile
+	if isYAMLPath(path) {
+		err = yaml.Unmarshal(data, &sf)
+	} else {
+		err = json.Unmarshal(data, &sf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	if sf.Name == "" || len(sf.Languages) == 0 {
+		return nil, fmt.Errorf("%s: name and languages are required", path)
+	}
+	return &sf, nil
+}
+
+// writeStandardFile encodes sf as JSON or YAML, chosen by the extension of
+// path. An empty path writes JSON to stdout.
+func writeStandardFile(path string, sf *standardFile) error {
+	var data []byte
+	var err error
+	if isYAMLPath(path) {
+		data, err = yaml.Marshal(sf)
+	} else {
+		data, err = json.MarshalIndent(sf, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("encoding coding standard: %w", err)
+	}
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// exportStandard reads the full configuration of a coding standard: its
+// languages, tools, and every pattern of its enabled tools with parameters.
+func exportStandard(client *codacy.Client, provider, orgName string, csID int64) (*standardFile, error) {
+	cs, err := client.GetCodingStandard(provider, orgName, csID)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := client.ListCodingStandardTools(provider, orgName, csID)
+	if err != nil {
+		return nil, err
+	}
+	names := toolNames(client)
+
+	sf := &standardFile{Name: cs.Name, Languages: cs.Languages}
+	for _, t := range tools {
+		tf := toolFile{UUID: t.UUID, Name: names[t.UUID], Enabled: t.IsEnabled}
+		if t.IsEnabled {
+			patterns, err := client.ListCodingStandardPatterns(provider, orgName, csID, t.UUID)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range patterns {
+				pf := patternFile{
+					ID:       p.PatternDefinition.ID,
+					Category: p.PatternDefinition.Category,
+					Enabled:  p.Enabled,
+				}
+				if len(p.Parameters) > 0 {
+					pf.Parameters = parameterMap(p.Parameters)
+				}
+				tf.Patterns = append(tf.Patterns, pf)
+			}
+		}
+		sf.Tools = append(sf.Tools, tf)
+	}
+	return sf, nil
+}
+
+// runExport implements the export command, which writes a coding standard to
+// a JSON or YAML file.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		csID   = fs.Int64("coding-standard-id", 0, "ID of the coding standard to export (required)")
+		output = fs.String("output", "", "File to write; .yaml/.yml selects YAML, anything else JSON (default: JSON on stdout)")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler export [flags]
+
+Writes a coding standard — languages, tools, patterns and parameters — to a
+JSON or YAML file that can be versioned and imported into any organisation.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *csID == 0 {
+		fs.Usage()
+		return fmt.Errorf("--coding-standard-id is required")
+	}
+
+	client := conn.client(token)
+	sf, err := exportStandard(client, *conn.provider, *conn.orgName, *csID)
+	if err != nil {
+		return err
+	}
+	if err := writeStandardFile(*output, sf); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Printf("Exported %q (%d tool(s)) to %s\n", sf.Name, len(sf.Tools), *output)
+	}
+	return nil
+}
+
+// runImport implements the import command, which creates or updates a coding
+// standard from a file through the draft/promote flow.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		input   = fs.String("input", "", "File to import; .yaml/.yml is read as YAML, anything else as JSON (required)")
+		csID    = fs.Int64("coding-standard-id", 0, "ID of the coding standard to update (default: the standard with the file's name, or a new one)")
+		promote = fs.Bool("promote", true, "Promote the draft after importing")
+		dryRun  = fs.Bool("dry-run", false, "Show what would be imported without making any changes")
+		yes     = fs.Bool("yes", false, "Import without asking for confirmation")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler import [flags]
+
+Creates or updates a coding standard from a file written by export. An
+existing standard is edited through a new draft, so that edits pending in
+other drafts of it are never promoted along with the file; tools missing
+from the file are disabled. The draft is then promoted.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *input == "" {
+		fs.Usage()
+		return fmt.Errorf("--input is required")
+	}
+	sf, err := readStandardFile(*input)
+	if err != nil {
+		return err
+	}
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+	provider, orgName := *conn.provider, *conn.orgName
+	client := conn.client(token)
+
+	all, err := client.ListCodingStandards(provider, orgName)
+	if err != nil {
+		return err
+	}
+	var existing *codacy.CodingStandard
+	for i, cs := range all {
+		if (*csID != 0 && cs.ID == *csID) || (*csID == 0 && !cs.IsDraft && cs.Name == sf.Name) {
+			existing = &all[i]
+			break
+		}
+	}
+	if *csID != 0 && existing == nil {
+		return fmt.Errorf("coding standard %d not found", *csID)
+	}
+
+	if existing != nil {
+		fmt.Printf("Updating %q (ID %d) from %s\n", existing.Name, existing.ID, *input)
+		if !sameLanguages(existing.Languages, sf.Languages) {
+			fmt.Printf("  Note: languages differ (%s in the file) and are kept as they are\n", strings.Join(sf.Languages, ", "))
+		}
+	} else {
+		fmt.Printf("Creating %q for %s from %s\n", sf.Name, strings.Join(sf.Languages, ", "), *input)
+	}
+	fmt.Printf("  %d tool(s) to configure; tools not in the file are disabled\n", len(sf.Tools))
+	if *promote {
+		fmt.Println("  The draft is promoted afterwards")
+	}
+	fmt.Println()
+	if *dryRun {
+		fmt.Printf("[dry-run] would configure %d tool(s)\n", len(sf.Tools))
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm("Import the file?") {
+		fmt.Println("Aborted — nothing changed.")
+		return nil
+	}
+
+	var draft codacy.CodingStandard
+	switch {
+	case existing == nil:
+		created, err := client.CreateCodingStandard(provider, orgName, sf.Name, sf.Languages)
+		if err != nil {
+			return err
+		}
+		draft = *created
+		fmt.Printf("  Draft created: ID %d\n", draft.ID)
+	case existing.IsDraft:
+		draft = *existing
+	default:
+		if draft, _, err = ensureDraft(client, logger.With(keyStandardID, existing.ID), provider, orgName, *existing, all, draftNew, false, nil); err != nil {
+			return err
+		}
+	}
+
+	failed, err := applyStandardFile(client, logger.With(keyDraftID, draft.ID), provider, orgName, draft.ID, sf)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Configured %d/%d tool(s)\n", len(sf.Tools)-len(failed), len(sf.Tools))
+	if len(failed) > 0 {
+		return fmt.Errorf("draft %d left unpromoted — could not configure %s", draft.ID, strings.Join(failed, ", "))
+	}
+
+	if !*promote {
+		fmt.Printf("  Draft %d left unpromoted (--promote=false)\n", draft.ID)
+		return nil
+	}
+	if _, err := client.PromoteDraftCodingStandard(provider, orgName, draft.ID); err != nil {
+		return err
+	}
+	fmt.Println("  Promoted successfully!")
+	return nil
+}
+
+// applyStandardFile configures the tools and patterns of the draft csID as
+// described by sf and disables tools sf does not list. It returns the UUIDs
+// of the tools that could not be configured.
+func applyStandardFile(client *codacy.Client, logger *slog.Logger, provider, orgName string, csID int64, sf *standardFile) ([]string, error) {
+	current, err := client.ListCodingStandardTools(provider, orgName, csID)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	for _, tf := range sf.Tools {
+		patterns := make([]codacy.PatternConfigurationBody, 0, len(tf.Patterns))
+		for _, pf := range tf.Patterns {
+			pc := codacy.PatternConfigurationBody{ID: pf.ID, Enabled: pf.Enabled}
+			for _, name := range sortedKeys(pf.Parameters) {
+				pc.Parameters = append(pc.Parameters, codacy.PatternParameter{Name: name, Value: pf.Parameters[name]})
+			}
+			patterns = append(patterns, pc)
+		}
+		if err := client.UpdateCodingStandardTool(provider, orgName, csID, tf.UUID, tf.Enabled, patterns); err != nil {
+			logger.Warn("Could not configure tool", keyToolUUID, tf.UUID, "error", err)
+			failed = append(failed, tf.UUID)
+		}
+	}
+	for _, t := range current {
+		listed := slices.ContainsFunc(sf.Tools, func(tf toolFile) bool { return tf.UUID == t.UUID })
+		if listed || !t.IsEnabled {
+			continue
+		}
+		if err := client.UpdateCodingStandardTool(provider, orgName, csID, t.UUID, false, nil); err != nil {
+			logger.Warn("Could not disable tool", keyToolUUID, t.UUID, "error", err)
+			failed = append(failed, t.UUID)
+		}
+	}
+	return failed, nil
+}
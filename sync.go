@@ -0,0 +1,251 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// syncToolPlan is the change sync makes to one tool of the target standard.
+type syncToolPlan struct {
+	uuid       string
+	enableTool bool // the tool is disabled in the target but has active patterns in the source
+	patterns   []codacy.PatternConfigurationBody
+}
+
+// runSync implements the sync command, which copies the state of the patterns
+// in the selected categories from a coding standard of one organisation to a
+// coding standard of another, matching tools by UUID.
+func runSync(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	var (
+		srcID       = fs.Int64("coding-standard-id", 0, "ID of the source coding standard (required)")
+		tgtToken    = fs.String("target-api-token", "", "API token for the target organisation (default: the source token)")
+		tgtProvider = fs.String("target-provider", "", "Git provider of the target organisation (default: --provider)")
+		tgtOrg      = fs.String("target-organization", "", "Target organisation name (required)")
+		tgtID       = fs.Int64("target-coding-standard-id", 0, "ID of the target coding standard (default: the target's effective standard with the source's name)")
+		categories  = fs.String("category", "Security", "Comma-separated pattern categories to copy")
+		promote     = fs.Bool("promote", true, "Promote the target draft after applying the changes")
+		yes         = fs.Bool("yes", false, "Apply without asking for confirmation")
+		dryRun      = fs.Bool("dry-run", false, "Only show the plan")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler sync [flags]
+
+Makes the patterns of the given categories (Security by default) in a
+target organisation's coding standard enabled exactly when they are in a
+source organisation's standard. Tools are matched by UUID. The plan is shown
+before anything changes; the target is edited through a draft and promoted.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *srcID == 0 || *tgtOrg == "" {
+		fs.Usage()
+		return fmt.Errorf("--coding-standard-id and --target-organization are required")
+	}
+	cats := splitList(*categories)
+	if len(cats) == 0 {
+		return fmt.Errorf("--category must name at least one category")
+	}
+	if *tgtToken == "" {
+		*tgtToken = token
+	}
+	if *tgtProvider == "" {
+		*tgtProvider = *conn.provider
+	}
+
+	// The draft is created in the target organisation, so log it as such.
+	logger, err := newLogger(os.Stderr, *conn.logLevel, *conn.logFormat)
+	if err != nil {
+		return err
+	}
+	logger = logger.With("provider", *tgtProvider, keyOrg, *tgtOrg)
+	src := conn.client(token)
+	tgt := conn.client(*tgtToken)
+
+	source, err := src.GetCodingStandard(*conn.provider, *conn.orgName, *srcID)
+	if err != nil {
+		return err
+	}
+	all, err := tgt.ListCodingStandards(*tgtProvider, *tgtOrg)
+	if err != nil {
+		return err
+	}
+	var target *codacy.CodingStandard
+	for i, cs := range all {
+		if (*tgtID != 0 && cs.ID == *tgtID) || (*tgtID == 0 && !cs.IsDraft && cs.Name == source.Name) {
+			target = &all[i]
+			break
+		}
+	}
+	if target == nil {
+		if *tgtID != 0 {
+			return fmt.Errorf("target coding standard %d not found", *tgtID)
+		}
+		return fmt.Errorf("no coding standard named %q in %s — use --target-coding-standard-id", source.Name, *tgtOrg)
+	}
+
+	fmt.Printf("Syncing %s patterns\n", strings.Join(cats, ", "))
+	fmt.Printf("  from %s/%s %q (ID %d)\n", *conn.provider, *conn.orgName, source.Name, source.ID)
+	fmt.Printf("  to   %s/%s %q (ID %d)\n", *tgtProvider, *tgtOrg, target.Name, target.ID)
+	fmt.Println()
+
+	plan, unmatched, err := planSync(src, *conn.provider, *conn.orgName, source.ID, tgt, *tgtProvider, *tgtOrg, target.ID, cats)
+	if err != nil {
+		return err
+	}
+	for _, uuid := range unmatched {
+		fmt.Printf("  Tool %s: not in the target standard, skipped\n", uuid)
+	}
+	if len(plan) == 0 {
+		fmt.Println("Target already matches the source — nothing to do.")
+		return nil
+	}
+	var changes int
+	for _, tp := range plan {
+		fmt.Printf("  Tool %s:\n", tp.uuid)
+		if tp.enableTool {
+			fmt.Println("      enable tool")
+		}
+		for _, p := range tp.patterns {
+			mark := "-"
+			if p.Enabled {
+				mark = "+"
+			}
+			fmt.Printf("      %s %s\n", mark, p.ID)
+		}
+		changes += len(tp.patterns)
+	}
+	fmt.Println()
+
+	if *dryRun {
+		fmt.Printf("[dry-run] would change %d pattern(s) in %d tool(s)\n", changes, len(plan))
+		return nil
+	}
+	if needsConfirmation(*yes, *dryRun) && !confirm(fmt.Sprintf("Change %d pattern(s) in %d tool(s) of %s/%s?", changes, len(plan), *tgtProvider, *tgtOrg)) {
+		fmt.Println("Aborted — nothing changed.")
+		return nil
+	}
+
+	// The plan was computed against the target itself, so it is applied to a
+	// fresh copy of it: an older draft may hold a different pattern state.
+	draft := *target
+	if !target.IsDraft {
+		if draft, _, err = ensureDraft(tgt, logger, *tgtProvider, *tgtOrg, *target, all, draftNew, false, nil); err != nil {
+			return err
+		}
+	}
+	// The draft was copied from the target, so tools disabled there are still
+	// disabled unless the plan enables them.
+	enabled := make(map[string]bool)
+	tools, err := tgt.ListCodingStandardTools(*tgtProvider, *tgtOrg, draft.ID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tools {
+		enabled[t.UUID] = t.IsEnabled
+	}
+
+	var failed []string
+	for _, tp := range plan {
+		if err := tgt.UpdateCodingStandardTool(*tgtProvider, *tgtOrg, draft.ID, tp.uuid, enabled[tp.uuid] || tp.enableTool, tp.patterns); err != nil {
+			logger.Warn("Could not update tool", keyDraftID, draft.ID, keyToolUUID, tp.uuid, "error", err)
+			failed = append(failed, tp.uuid)
+		}
+	}
+	fmt.Printf("Updated %d/%d tool(s)\n", len(plan)-len(failed), len(plan))
+	if len(failed) > 0 {
+		return fmt.Errorf("draft %d left unpromoted — could not update %s", draft.ID, strings.Join(failed, ", "))
+	}
+
+	if !*promote {
+		fmt.Printf("Draft %d left unpromoted (--promote=false)\n", draft.ID)
+		return nil
+	}
+	if _, err := tgt.PromoteDraftCodingStandard(*tgtProvider, *tgtOrg, draft.ID); err != nil {
+		return err
+	}
+	fmt.Println("Promoted successfully!")
+	return nil
+}
+
+// planSync compares the patterns in categories of the source and target
+// standards and returns, per tool present in both, the patterns whose enabled
+// state must change in the target. It also returns the UUIDs of source tools
+// missing from the target.
+func planSync(
+	src *codacy.Client, srcProvider, srcOrg string, srcID int64,
+	tgt *codacy.Client, tgtProvider, tgtOrg string, tgtID int64,
+	categories []string,
+) (plan []syncToolPlan, unmatched []string, err error) {
+	srcTools, err := src.ListCodingStandardTools(srcProvider, srcOrg, srcID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tgtTools, err := tgt.ListCodingStandardTools(tgtProvider, tgtOrg, tgtID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tgtEnabled := make(map[string]bool)
+	for _, t := range tgtTools {
+		tgtEnabled[t.UUID] = t.IsEnabled
+	}
+
+	for _, st := range srcTools {
+		targetToolEnabled, ok := tgtEnabled[st.UUID]
+		if !ok {
+			unmatched = append(unmatched, st.UUID)
+			continue
+		}
+		srcPatterns, err := src.ListCodingStandardPatterns(srcProvider, srcOrg, srcID, st.UUID)
+		if err != nil {
+			return nil, nil, err
+		}
+		tgtPatterns, err := tgt.ListCodingStandardPatterns(tgtProvider, tgtOrg, tgtID, st.UUID)
+		if err != nil {
+			return nil, nil, err
+		}
+		want := make(map[string]bool)
+		for _, p := range srcPatterns {
+			if containsFold(categories, p.PatternDefinition.Category) {
+				// A pattern of a disabled tool is not in effect.
+				want[p.PatternDefinition.ID] = st.IsEnabled && p.Enabled
+			}
+		}
+
+		tp := syncToolPlan{uuid: st.UUID}
+		var anyEnabled bool
+		for _, p := range tgtPatterns {
+			id := p.PatternDefinition.ID
+			w, ok := want[id]
+			if !ok {
+				continue
+			}
+			anyEnabled = anyEnabled || w
+			if p.Enabled != w {
+				tp.patterns = append(tp.patterns, codacy.PatternConfigurationBody{ID: id, Enabled: w})
+			}
+		}
+		tp.enableTool = anyEnabled && !targetToolEnabled
+		if tp.enableTool || len(tp.patterns) > 0 {
+			plan = append(plan, tp)
+		}
+	}
+	return plan, unmatched, nil
+}
@@ -0,0 +1,914 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/codacy/codacy-security-toggler/codacy"
+)
+
+// runToggle implements the toggle command, which toggles the patterns of the
+// selected categories across coding standards and repositories. It is also
+// what runs when no command is given.
+func runToggle(args []string) error {
+	fs := flag.NewFlagSet("toggle", flag.ExitOnError)
+	conn := addConnFlags(fs)
+	provider, orgName := conn.provider, conn.orgName
+	var (
+		csID      = fs.Int64("coding-standard-id", 0, "ID of the coding standard to process (0 = all standards)")
+		enable    = fs.Bool("enable", true, "true = enable security patterns, false = disable them")
+		promote   = fs.Bool("promote", true, "Promote the draft after updating patterns")
+		skipLive  = fs.Bool("skip-live", false, "Skip coding standards that are not drafts (instead of duplicating them)")
+		onFail    = fs.String("on-tool-failure", failSkipPromote, "What to do with a draft when some tools could not be updated: promote it anyway, skip-promote (leave it for --resume), or delete-draft")
+		strategy  = fs.String("draft-strategy", draftNew, "For standards that are not drafts: always create a new draft, reuse an existing draft of the standard, or replace it (new|reuse|replace)")
+		dryRun    = fs.Bool("dry-run", false, "Print what would happen without making any changes")
+		verbose   = fs.Bool("verbose", false, "Log additional detail such as every tool update (same as --log-level=debug)")
+		jPath     = fs.String("journal", "", "Path of the run journal (default: codacy-security-toggler-<timestamp>.journal)")
+		resume    = fs.String("resume", "", "Resume an interrupted run from its journal, skipping completed steps")
+		repPath   = fs.String("report", "", "Write a JSON report of the run's outcome to this file")
+		retries   = fs.Int("promote-retries", 2, "Times to retry applying a promoted standard to the repositories it failed for")
+		fallback  = fs.Bool("fallback-patch", false, "Patch the patterns of repositories a promoted standard could not be applied to directly")
+		langAware = fs.Bool("language-aware", true, "Only toggle the tools of a detached repository that support at least one of its languages")
+		override  = fs.String("overridden-tools", overridesIgnore, "Tools of repositories following a standard that do not follow it: ignore, report, patch them directly, or reattach the repository to its standard (all but ignore list the tools of every such repository)")
+		yes       = fs.Bool("yes", false, "Do not ask for confirmation before making changes")
+		cats      = fs.String("categories", "Security", "Comma-separated pattern categories to toggle")
+		workers   = fs.Int("concurrency", 1, "Number of tools updated in parallel within a standard or repository")
+		stdNames  = fs.String("standards", "", "Comma-separated names of the coding standards to process; shell patterns such as 'Backend*' are allowed (default: all)")
+		repoNames = fs.String("repositories", "", "Comma-separated names of the repositories handled in the repository phase; shell patterns are allowed (default: all)")
+		phaseList = fs.String("phases", "", "Comma-separated phases to run: standards, detached (default: both, or only standards with --coding-standard-id)")
+	)
+	fs.Usage = toggleUsage(fs)
+	if err := parseArgs(fs, args); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if err := conn.setup(); err != nil {
+		fs.Usage()
+		return err
+	}
+	token := conn.token()
+	if *verbose {
+		*conn.logLevel = "debug"
+	}
+	logger, err := conn.logger()
+	if err != nil {
+		return err
+	}
+
+	if !validDraftStrategy(*strategy) {
+		return fmt.Errorf("invalid --draft-strategy %q — use reuse, replace or new", *strategy)
+	}
+	if !validOverridesMode(*override) {
+		return fmt.Errorf("invalid --overridden-tools %q — use ignore, report, patch or reattach", *override)
+	}
+	if !validToolFailurePolicy(*onFail) {
+		return fmt.Errorf("invalid --on-tool-failure %q — use promote, skip-promote or delete-draft", *onFail)
+	}
+	categories := splitList(*cats)
+	if len(categories) == 0 {
+		return errors.New("--categories must name at least one category")
+	}
+	if *workers < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	phases, err := parsePhases(*phaseList, *csID != 0)
+	if err != nil {
+		return err
+	}
+	runStandards, runDetached := slices.Contains(phases, phaseStandards), slices.Contains(phases, phaseDetached)
+	if *resume != "" && *jPath != "" && *resume != *jPath {
+		return errors.New("--journal and --resume must not point to different files")
+	}
+
+	action := "enable"
+	if !*enable {
+		action = "disable"
+	}
+
+	journalPath := *resume
+	if journalPath == "" {
+		journalPath = *jPath
+	}
+	if journalPath == "" {
+		journalPath = fmt.Sprintf("codacy-security-toggler-%s.journal", time.Now().Format("20060102-150405"))
+	}
+	jr, err := openJournal(journalPath, *resume != "", *dryRun, *provider, *orgName, *enable, categories)
+	if err != nil {
+		return err
+	}
+	defer jr.Close()
+
+	if *dryRun {
+		logger.Info("Starting dry run — no changes will be made",
+			"action", action, "categories", categories, "promote", *promote, "phases", phases)
+	} else {
+		logger.Info("Starting run",
+			"action", action, "categories", categories, "promote", *promote, "phases", phases, "journal", journalPath)
+	}
+	if *resume != "" {
+		logger.Info("Resuming run",
+			"drafts", len(jr.drafts), "tool_updates", len(jr.tools),
+			"promotions", len(jr.promoted), "repository_tool_updates", len(jr.repoTools))
+	}
+
+	client := conn.client(token)
+
+	var standards, all []codacy.CodingStandard
+	if runStandards {
+		standards, all, err = resolveStandards(client, *provider, *orgName, *csID)
+		if err != nil {
+			return err
+		}
+		if *strategy != draftNew {
+			standards = withoutLinkedDrafts(standards, all)
+		}
+		standards = withoutJournaledDrafts(standards, jr)
+		standards = filterStandards(standards, splitList(*stdNames))
+		if len(standards) == 0 {
+			logger.Info("No coding standards found")
+		}
+		for _, cs := range standards {
+			logger.Info("Selected coding standard", keyStandardID, cs.ID, "name", cs.Name,
+				"draft", cs.IsDraft, "default", cs.IsDefault,
+				"tools", cs.Meta.EnabledToolsCount, "patterns", cs.Meta.EnabledPatternsCount)
+		}
+	}
+
+	opts := toggleOptions{
+		enable:          *enable,
+		promote:         *promote,
+		skipLive:        *skipLive,
+		strategy:        *strategy,
+		onToolFailure:   *onFail,
+		promoteRetries:  *retries,
+		fallbackPatch:   *fallback,
+		overriddenTools: *override,
+		languageAware:   *langAware,
+		dryRun:          *dryRun,
+		categories:      categories,
+		concurrency:     *workers,
+	}
+
+	// With nothing to change there is nothing to confirm, but the summary
+	// and the report are still written.
+	nothingToDo := len(standards) == 0 && !runDetached
+	if !nothingToDo && needsConfirmation(*yes, opts.dryRun) {
+		// Repositories are listed for the summary only: promotion replaces
+		// the standards they follow, so phase 2 lists them again.
+		var repos []codacy.RepositoryWithAnalysis
+		var reposErr error
+		if runDetached {
+			repos, reposErr = client.ListRepositoriesWithAnalysis(*provider, *orgName)
+			if reposErr != nil {
+				logger.Warn("Could not list repositories", "error", reposErr)
+			}
+			repos = filterRepositories(repos, splitList(*repoNames))
+		}
+		printPlanSummary(*orgName, standards, all, runDetached, repos, reposErr, opts)
+		if !confirm("Proceed?") {
+			fmt.Println("Aborted — no changes made.")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	report := &runReport{
+		Provider:     *provider,
+		Organization: *orgName,
+		Enable:       *enable,
+		DryRun:       *dryRun,
+		Phases:       phases,
+		StartedAt:    time.Now().UTC(),
+		Standards:    []standardReport{},
+		Detached:     []repositoryReport{},
+	}
+
+	var hadError bool
+	if runStandards {
+		phaseClient, span := startSpan(client, "phase standards", attribute.Int("standards", len(standards)))
+		for _, cs := range standards {
+			sr := standardReport{ID: cs.ID, Name: cs.Name}
+			if err := processStandard(phaseClient, logger, *provider, *orgName, cs, all, opts, jr, &sr); err != nil {
+				logger.Error("Could not process coding standard", keyStandardID, cs.ID, "name", cs.Name, "error", err)
+				sr.Error = err.Error()
+				hadError = true
+			}
+			if len(sr.FailedTools) > 0 && !sr.Promoted && opts.promote {
+				hadError = true
+			}
+			for _, f := range sr.FailedRepositories {
+				if !f.Resolved {
+					hadError = true
+				}
+			}
+			report.Standards = append(report.Standards, sr)
+		}
+		span.End()
+	}
+
+	// Phase 2: repositories not covered by any coding standard, and tools
+	// overriding the standard of the repository they belong to.
+	if runDetached {
+		repos, err := client.ListRepositoriesWithAnalysis(*provider, *orgName)
+		if err != nil {
+			logger.Error("Could not list repositories", "error", err)
+			hadError = true
+		} else {
+			repos = filterRepositories(repos, splitList(*repoNames))
+			phaseClient, span := startSpan(client, "phase detached")
+			logger.Info("Processing detached repositories (not following any coding standard)")
+			err := processDetachedRepositories(phaseClient, logger, *provider, *orgName, repos, opts, jr, report)
+			if err != nil {
+				logger.Error("Could not process detached repositories", "error", err)
+				hadError = true
+			}
+			endSpan(span, err)
+			if opts.overriddenTools != overridesIgnore {
+				phaseClient, span := startSpan(client, "phase overridden tools", attribute.String("mode", opts.overriddenTools))
+				logger.Info("Processing tools overriding their repository's coding standard")
+				err := processOverriddenTools(phaseClient, logger, *provider, *orgName, repos, opts, jr, report)
+				if err != nil {
+					logger.Error("Could not process overridden tools", "error", err)
+					hadError = true
+				}
+				endSpan(span, err)
+			}
+		}
+	}
+
+	// New repositories only inherit the toggled patterns through the default
+	// standard. Promotion replaces standards, so list them again.
+	if current, err := client.ListCodingStandards(*provider, *orgName); err != nil {
+		logger.Warn("Could not check the default coding standard", "error", err)
+	} else if def, ok := defaultStandard(current); ok {
+		report.DefaultStandardID = def.ID
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	report.printSummary()
+	if *repPath != "" {
+		if err := report.write(*repPath); err != nil {
+			logger.Error("Could not write the report", "error", err)
+			hadError = true
+		} else {
+			logger.Info("Report written", "path", *repPath)
+		}
+	}
+
+	if hadError {
+		return errors.New("the run finished with errors — see above")
+	}
+	return nil
+}
+
+// toggleUsage returns the usage function of the toggle command.
+func toggleUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Usage: codacy-security-toggler toggle [flags]
+
+Toggles Security-category code patterns across all tools of one or more
+coding standards in a Codacy organisation, then optionally promotes the
+updated draft to an effective coding standard. Repositories that follow no
+coding standard are then patched directly.
+
+The command name may be omitted: codacy-security-toggler [flags] runs toggle.
+
+Flags:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+
+  # Enable security patterns on all coding standards and promote each draft
+  codacy-security-toggler toggle \
+    --api-token=$CODACY_API_TOKEN \
+    --provider=gh \
+    --organization=my-org \
+    --enable=true
+
+  # Disable security patterns on a specific coding standard (dry run first)
+  codacy-security-toggler toggle \
+    --api-token=$CODACY_API_TOKEN \
+    --organization=my-org \
+    --coding-standard-id=42 \
+    --enable=false \
+    --dry-run
+
+  # Enable without promoting (leave as draft for review)
+  codacy-security-toggler toggle \
+    --api-token=$CODACY_API_TOKEN \
+    --organization=my-org \
+    --enable=true \
+    --promote=false
+
+  # Resume a run that was interrupted, skipping the steps it completed
+  codacy-security-toggler toggle \
+    --api-token=$CODACY_API_TOKEN \
+    --organization=my-org \
+    --resume=codacy-security-toggler-20240101-120000.journal
+
+  # Take the organisation, API URL and other settings from a profile
+  codacy-security-toggler toggle --profile=acme
+`)
+	}
+}
+
+// toggleOptions holds the flags that control how patterns are toggled.
+type toggleOptions struct {
+	enable   bool
+	promote  bool
+	skipLive bool
+	strategy string
+	dryRun   bool
+
+	// onToolFailure is the failure policy applied when some tools of a draft
+	// could not be updated.
+	onToolFailure string
+
+	// promoteRetries and fallbackPatch control how repositories a promoted
+	// standard could not be applied to are handled.
+	promoteRetries int
+	fallbackPatch  bool
+
+	// overriddenTools selects how tools that do not follow the standard of
+	// their repository are handled.
+	overriddenTools string
+
+	// languageAware restricts detached repositories to the tools supporting
+	// the languages present in them.
+	languageAware bool
+
+	// categories are the pattern categories toggled, Security by default.
+	categories []string
+
+	// concurrency is the number of tools updated in parallel.
+	concurrency int
+}
+
+// patterns describes the patterns being toggled, for progress output.
+func (o toggleOptions) patterns() string {
+	if len(o.categories) == 1 && strings.EqualFold(o.categories[0], "Security") {
+		return "security patterns"
+	}
+	return strings.Join(o.categories, ", ") + " patterns"
+}
+
+// Policies for a draft in which some tools could not be updated.
+const (
+	failPromote     = "promote"      // promote the partially updated draft anyway
+	failSkipPromote = "skip-promote" // leave the draft unpromoted
+	failDeleteDraft = "delete-draft" // delete the draft created for the run
+)
+
+func validToolFailurePolicy(s string) bool {
+	return s == failPromote || s == failSkipPromote || s == failDeleteDraft
+}
+
+// printPlanSummary describes the changes a toggle run is about to make so that
+// they can be confirmed before anything is changed.
+func printPlanSummary(orgName string, standards, all []codacy.CodingStandard, detached bool, repos []codacy.RepositoryWithAnalysis, reposErr error, opts toggleOptions) {
+	action := "ENABLE"
+	if !opts.enable {
+		action = "DISABLE"
+	}
+	var live, drafts, linked int
+	var existing []int64 // drafts of the live standards, reused or replaced
+	for _, cs := range standards {
+		switch {
+		case cs.IsDraft:
+			drafts++
+		case opts.skipLive:
+			continue
+		default:
+			live++
+			for _, d := range linkedDrafts(cs, all) {
+				existing = append(existing, d.ID)
+			}
+		}
+		linked += cs.Meta.LinkedRepositoriesCount
+	}
+
+	verb := "promote"
+	if !opts.promote {
+		verb = "leave unpromoted"
+	}
+	fmt.Printf("About to %s %s in %s:\n", action, opts.patterns(), orgName)
+	if len(standards) > 0 {
+		fmt.Printf("  %d coding standard(s) to draft and %s\n", live, verb)
+		if drafts > 0 {
+			fmt.Printf("  %d draft(s) to update and %s\n", drafts, verb)
+		}
+		if len(existing) > 0 {
+			switch opts.strategy {
+			case draftReplace:
+				fmt.Printf("  %d existing draft(s) to DELETE first (--draft-strategy=replace): %v\n", len(existing), existing)
+			case draftReuse:
+				fmt.Printf("  existing drafts to reuse with any edits already made in them (--draft-strategy=reuse): %v\n", existing)
+			}
+		}
+		if opts.onToolFailure == failDeleteDraft {
+			fmt.Println("  drafts created by the run to DELETE if some of their tools fail to update (--on-tool-failure=delete-draft)")
+		}
+		if opts.promote {
+			fmt.Printf("  %d linked repository(ies) affected by the promotions\n", linked)
+			if opts.fallbackPatch {
+				fmt.Println("  repositories a promoted standard cannot be applied to are patched directly (--fallback-patch)")
+			}
+		}
+	}
+	if detached {
+		if reposErr != nil {
+			fmt.Println("  detached repositories: unknown (listing failed)")
+		} else {
+			fmt.Printf("  %d detached repository(ies) to patch directly\n", len(detachedRepositories(repos)))
+		}
+		following := "unknown number of"
+		if reposErr == nil {
+			following = fmt.Sprint(len(repos) - len(detachedRepositories(repos)))
+		}
+		switch opts.overriddenTools {
+		case overridesPatch:
+			fmt.Printf("  tools overriding their standard in %s repository(ies) following one to patch directly (--overridden-tools=patch)\n", following)
+		case overridesReattach:
+			fmt.Printf("  %s repository(ies) following a standard to RE-ATTACH to it if some tools override it, dropping their local tool settings (--overridden-tools=reattach)\n", following)
+		}
+	}
+	fmt.Println()
+}
+
+// Phases of a toggle run.
+const (
+	phaseStandards = "standards" // draft, toggle and promote coding standards
+	phaseDetached  = "detached"  // patch repositories directly
+)
+
+// parsePhases parses the value of --phases. Without one, both phases run,
+// except that a run for a single standard leaves repositories alone.
+func parsePhases(s string, singleStandard bool) ([]string, error) {
+	if s == "" {
+		if singleStandard {
+			return []string{phaseStandards}, nil
+		}
+		return []string{phaseStandards, phaseDetached}, nil
+	}
+	var phases []string
+	for _, p := range splitList(s) {
+		p = strings.ToLower(p)
+		if p != phaseStandards && p != phaseDetached {
+			return nil, fmt.Errorf("invalid phase %q in --phases — use standards, detached or both", p)
+		}
+		if !slices.Contains(phases, p) {
+			phases = append(phases, p)
+		}
+	}
+	if len(phases) == 0 {
+		return nil, errors.New("--phases must name at least one phase")
+	}
+	return phases, nil
+}
+
+// withoutJournaledDrafts drops from selected the drafts the journal records
+// for another selected standard: they are processed through that standard,
+// and would otherwise be processed twice when a run is resumed.
+func withoutJournaledDrafts(selected []codacy.CodingStandard, jr *journal) []codacy.CodingStandard {
+	drafts := make(map[int64]bool)
+	for _, cs := range selected {
+		if id, _, ok := jr.draftFor(cs.ID); ok && id != cs.ID {
+			drafts[id] = true
+		}
+	}
+	var out []codacy.CodingStandard
+	for _, cs := range selected {
+		if !drafts[cs.ID] {
+			out = append(out, cs)
+		}
+	}
+	return out
+}
+
+// filterStandards returns the standards whose name matches one of patterns.
+func filterStandards(standards []codacy.CodingStandard, patterns []string) []codacy.CodingStandard {
+	var kept []codacy.CodingStandard
+	for _, cs := range standards {
+		if matchesAny(patterns, cs.Name) {
+			kept = append(kept, cs)
+		}
+	}
+	return kept
+}
+
+// filterRepositories returns the repositories whose name matches one of patterns.
+func filterRepositories(repos []codacy.RepositoryWithAnalysis, patterns []string) []codacy.RepositoryWithAnalysis {
+	var kept []codacy.RepositoryWithAnalysis
+	for _, r := range repos {
+		if matchesAny(patterns, r.Repository.Name) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// resolveStandards returns the list of coding standards to operate on along
+// with every standard of the organisation. When id > 0 only that standard is
+// selected; otherwise all standards are.
+func resolveStandards(client *codacy.Client, provider, orgName string, id int64) (selected, all []codacy.CodingStandard, err error) {
+	all, err = client.ListCodingStandards(provider, orgName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if id == 0 {
+		return all, all, nil
+	}
+	for _, cs := range all {
+		if cs.ID == id {
+			return []codacy.CodingStandard{cs}, all, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("coding standard %d not found", id)
+}
+
+// processStandard runs the full toggle-and-promote workflow for one coding standard
+// and records its outcome in rep. Steps already recorded in the journal are skipped.
+func processStandard(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName string,
+	cs codacy.CodingStandard,
+	all []codacy.CodingStandard,
+	opts toggleOptions,
+	jr *journal,
+	rep *standardReport,
+) (err error) {
+	client, span := startSpan(client, "coding standard",
+		attribute.Int64(keyStandardID, cs.ID), attribute.String("name", cs.Name))
+	defer func() { endSpan(span, err) }()
+	logger = logger.With(keyStandardID, cs.ID)
+	logger.Info("Processing coding standard", "name", cs.Name)
+
+	// A promoted draft becomes the effective standard, keeping its ID.
+	if jr.isPromoted(cs.ID) {
+		rep.Skipped = "already promoted according to the journal"
+		logger.Info("Skipping coding standard", "reason", rep.Skipped)
+		return nil
+	}
+
+	target, created := cs, false
+
+	// Non-draft standards require a new draft to be created before they can be edited.
+	if !cs.IsDraft {
+		if opts.skipLive {
+			rep.Skipped = "not a draft and --skip-live is set"
+			logger.Info("Skipping coding standard", "reason", rep.Skipped)
+			return nil
+		}
+		draft, isNew, err := ensureDraft(client, logger, provider, orgName, cs, all, opts.strategy, opts.dryRun, jr)
+		if err != nil {
+			return err
+		}
+		target, created = draft, isNew
+		if target.ID != cs.ID {
+			rep.DraftID = target.ID
+		}
+		if jr.isPromoted(target.ID) {
+			rep.Skipped = "draft already promoted according to the journal"
+			logger.Info("Skipping coding standard", keyDraftID, target.ID, "reason", rep.Skipped)
+			return nil
+		}
+	}
+	logger = logger.With(keyDraftID, target.ID)
+	span.SetAttributes(attribute.Int64(keyDraftID, target.ID))
+
+	// List all tools in the (draft) coding standard.
+	tools, err := client.ListCodingStandardTools(provider, orgName, target.ID)
+	if err != nil {
+		return fmt.Errorf("listing tools: %w", err)
+	}
+	logger.Info("Listed tools", "count", len(tools))
+
+	// Bulk-update the patterns of each tool.
+	failed := make([]bool, len(tools))
+	var journalErr error
+	var mu sync.Mutex
+	runConcurrently(len(tools), opts.concurrency, func(i int) {
+		tool := tools[i]
+		toolLogger := logger.With(keyToolUUID, tool.UUID)
+		if jr.toolDone(target.ID, tool.UUID) {
+			toolLogger.Debug("Tool already updated according to the journal")
+			return
+		}
+		if opts.dryRun {
+			toolLogger.Info("[dry-run] Would update tool patterns", "enable", opts.enable, "categories", opts.categories)
+			return
+		}
+		toolLogger.Debug("Updating tool patterns", "enable", opts.enable, "categories", opts.categories)
+		if err := client.UpdateCategoryPatterns(provider, orgName, target.ID, tool.UUID, opts.categories, opts.enable); err != nil {
+			toolLogger.Warn("Could not update tool", "error", err)
+			toolsFailed.inc(targetStandard)
+			failed[i] = true
+			return
+		}
+		toolsUpdated.inc(targetStandard)
+		if err := jr.recordTool(target.ID, tool.UUID); err != nil {
+			mu.Lock()
+			journalErr = err
+			mu.Unlock()
+		}
+	})
+	if journalErr != nil {
+		return journalErr
+	}
+	var failedTools []string
+	for i, tool := range tools {
+		if failed[i] {
+			failedTools = append(failedTools, tool.UUID)
+		}
+	}
+
+	updated := len(tools) - len(failedTools)
+	rep.ToolsUpdated = updated
+	rep.FailedTools = failedTools
+	if len(failedTools) > 0 {
+		logger.Warn("Some tools were not updated", "updated", updated, "total", len(tools), "failed_tools", failedTools)
+	} else {
+		logger.Info("Updated tools", "updated", updated, "total", len(tools), "enable", opts.enable, "categories", opts.categories)
+	}
+
+	if !opts.promote {
+		rep.PromotionWithheld = "--promote=false"
+	} else if len(failedTools) > 0 && opts.onToolFailure != failPromote {
+		// Promoting now would ship a half-toggled standard to every linked repository.
+		rep.PromotionWithheld = fmt.Sprintf("%d of %d tool(s) failed to update (--on-tool-failure=%s)",
+			len(failedTools), len(tools), opts.onToolFailure)
+		logger.Warn("Not promoting", "reason", rep.PromotionWithheld)
+		if opts.onToolFailure == failDeleteDraft {
+			if err := deleteRunDraft(client, logger, provider, orgName, target, created, opts.dryRun); err != nil {
+				return err
+			}
+			rep.DraftDeleted = created && !opts.dryRun
+			if rep.DraftDeleted {
+				if err := jr.recordDraftDeleted(cs.ID, target.ID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// Promote the draft to an effective coding standard.
+	if opts.promote {
+		if opts.dryRun {
+			logger.Info("[dry-run] Would promote draft")
+			rep.PromotionWithheld = "dry run"
+			return nil
+		}
+		logger.Info("Promoting draft")
+		result, err := client.PromoteDraftCodingStandard(provider, orgName, target.ID)
+		if err != nil {
+			return fmt.Errorf("promoting standard: %w", err)
+		}
+		rep.Promoted = true
+		standardsPromoted.inc()
+		if err := jr.recordPromotion(target.ID); err != nil {
+			return err
+		}
+		rep.AppliedRepositories = len(result.Successful)
+		logger.Info("Promoted draft", "applied_repositories", result.Successful)
+		if len(result.Failed) > 0 {
+			logger.Warn("Promotion could not apply the standard to some repositories", "failed_repositories", result.Failed)
+			rep.FailedRepositories = handlePromotionFailures(
+				client, logger, provider, orgName, target.ID, result.Failed, opts, jr)
+			for _, f := range rep.FailedRepositories {
+				if !f.Resolved {
+					logger.Error("Standard still not applied", keyRepo, f.Name, "reason", f.Reason)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// deleteRunDraft deletes the draft that was edited, if this tool created it.
+// A standard that was itself a draft, or a draft that was reused, may hold
+// someone else's work and is never deleted.
+func deleteRunDraft(client *codacy.Client, logger *slog.Logger, provider, orgName string, draft codacy.CodingStandard, created, dryRun bool) error {
+	if !created {
+		logger.Info("Keeping draft — it was not created by this tool")
+		return nil
+	}
+	if dryRun {
+		logger.Info("[dry-run] Would delete draft")
+		return nil
+	}
+	if err := client.DeleteCodingStandard(provider, orgName, draft.ID); err != nil {
+		return fmt.Errorf("deleting draft: %w", err)
+	}
+	logger.Info("Deleted draft")
+	return nil
+}
+
+// processDetachedRepositories handles repositories that are not covered by any
+// coding standard by toggling the patterns of the selected categories directly.
+// Tools already recorded in the journal are skipped and each repository's
+// outcome is appended to report.
+func processDetachedRepositories(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName string,
+	repos []codacy.RepositoryWithAnalysis,
+	opts toggleOptions,
+	jr *journal,
+	report *runReport,
+) error {
+	detached := detachedRepositories(repos)
+	if len(detached) == 0 {
+		logger.Info("No detached repositories found")
+		return nil
+	}
+
+	names := make([]string, len(detached))
+	for i, r := range detached {
+		names[i] = r.Repository.Name
+	}
+	logger.Info("Found detached repositories", "count", len(detached), "repositories", names)
+
+	var catalogue toolLanguages
+	if opts.languageAware {
+		var err error
+		if catalogue, err = loadToolLanguages(client); err != nil {
+			return fmt.Errorf("listing tool languages: %w", err)
+		}
+	}
+
+	var hadError bool
+	for _, r := range detached {
+		repoName := r.Repository.Name
+		repoLogger := logger.With(keyRepo, repoName)
+		client, span := startSpan(client, "repository", attribute.String(keyRepo, repoName))
+
+		tools, err := client.ListRepositoryTools(provider, orgName, repoName)
+		if err != nil {
+			repoLogger.Error("Could not list tools", "error", err)
+			report.Detached = append(report.Detached, repositoryReport{Name: repoName, Error: err.Error()})
+			hadError = true
+			endSpan(span, err)
+			continue
+		}
+		repoLogger.Info("Listed tools", "count", len(tools))
+
+		var skipped []string
+		if opts.languageAware {
+			var irrelevant []codacy.AnalysisTool
+			tools, irrelevant = catalogue.relevantTools(tools, r.Repository.Languages)
+			for _, t := range irrelevant {
+				skipped = append(skipped, t.Name)
+			}
+			if len(skipped) > 0 {
+				repoLogger.Info("Skipped tools not supporting the repository's languages",
+					"languages", r.Repository.Languages, "skipped_tools", skipped)
+			}
+		}
+
+		failedTools, err := patchTools(client, repoLogger, provider, orgName, repoName, tools, opts, jr)
+		rr := repositoryReport{
+			Name:         repoName,
+			ToolsUpdated: len(tools) - len(failedTools),
+			FailedTools:  failedTools,
+			SkippedTools: skipped,
+		}
+		if err != nil {
+			repoLogger.Error("Could not update repository", "error", err)
+			rr.Error = err.Error()
+			hadError = true
+		} else if len(failedTools) == 0 && !opts.dryRun {
+			detachedPatched.inc()
+		}
+		report.Detached = append(report.Detached, rr)
+		endSpan(span, err)
+	}
+
+	if hadError {
+		return fmt.Errorf("one or more detached repositories could not be fully updated")
+	}
+	return nil
+}
+
+// detachedRepositories returns the repositories that do not follow any coding standard.
+func detachedRepositories(repos []codacy.RepositoryWithAnalysis) []codacy.RepositoryWithAnalysis {
+	var detached []codacy.RepositoryWithAnalysis
+	for _, r := range repos {
+		if len(r.Repository.Standards) == 0 {
+			detached = append(detached, r)
+		}
+	}
+	return detached
+}
+
+// patchRepositoryTools toggles the patterns of the selected categories of
+// every tool of a repository directly through the repository patterns
+// endpoint, skipping tools already recorded in the journal. It returns the
+// number of tools found and the UUIDs of the tools that could not be updated.
+func patchRepositoryTools(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName, repoName string,
+	opts toggleOptions,
+	jr *journal,
+) (total int, failedTools []string, err error) {
+	tools, err := client.ListRepositoryTools(provider, orgName, repoName)
+	if err != nil {
+		return 0, nil, fmt.Errorf("listing tools for %s: %w", repoName, err)
+	}
+	logger.Info("Listed tools", "count", len(tools))
+	failedTools, err = patchTools(client, logger, provider, orgName, repoName, tools, opts, jr)
+	return len(tools), failedTools, err
+}
+
+// patchTools toggles the patterns of the selected categories of the given
+// tools of a repository directly, skipping tools already recorded in the
+// journal, and returns the UUIDs of the tools that could not be updated.
+func patchTools(
+	client *codacy.Client,
+	logger *slog.Logger,
+	provider, orgName, repoName string,
+	tools []codacy.AnalysisTool,
+	opts toggleOptions,
+	jr *journal,
+) (failedTools []string, err error) {
+	failed := make([]bool, len(tools))
+	var journalErr error
+	var mu sync.Mutex
+	runConcurrently(len(tools), opts.concurrency, func(i int) {
+		tool := tools[i]
+		toolLogger := logger.With(keyToolUUID, tool.UUID, "tool", tool.Name)
+		if jr.repoToolDone(repoName, tool.UUID) {
+			toolLogger.Debug("Tool already updated according to the journal")
+			return
+		}
+		if opts.dryRun {
+			toolLogger.Info("[dry-run] Would update tool patterns", "enable", opts.enable, "categories", opts.categories)
+			return
+		}
+		toolLogger.Debug("Updating tool patterns", "enable", opts.enable, "categories", opts.categories)
+		if err := client.UpdateRepositoryCategoryPatterns(provider, orgName, repoName, tool.UUID, opts.categories, opts.enable); err != nil {
+			toolLogger.Warn("Could not update tool", "error", err)
+			toolsFailed.inc(targetRepository)
+			failed[i] = true
+			return
+		}
+		toolsUpdated.inc(targetRepository)
+		if err := jr.recordRepoTool(repoName, tool.UUID); err != nil {
+			mu.Lock()
+			journalErr = err
+			mu.Unlock()
+		}
+	})
+	for i, tool := range tools {
+		if failed[i] {
+			failedTools = append(failedTools, tool.UUID)
+		}
+	}
+	if journalErr != nil {
+		return failedTools, journalErr
+	}
+
+	updated := len(tools) - len(failedTools)
+	if len(failedTools) > 0 {
+		logger.Warn("Some tools were not updated", "updated", updated, "total", len(tools), "failed_tools", failedTools)
+	} else {
+		logger.Info("Updated tools", "updated", updated, "total", len(tools), "enable", opts.enable, "categories", opts.categories)
+	}
+	return failedTools, nil
+}
+
+// runConcurrently calls fn for every index in [0, n) using at most workers
+// goroutines at a time, and returns once all calls have returned.
+func runConcurrently(n, workers int, fn func(i int)) {
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}